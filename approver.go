@@ -0,0 +1,60 @@
+package buyer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/url"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// ErrPaymentDenied is returned when an Approver refuses to allow a payment.
+var ErrPaymentDenied = errors.New("payment denied")
+
+// An Approver decides whether a payment may be made.  The Approver is
+// called before the payment is signed and receives the URL of the request
+// that requires payment, the requirements that were selected and the amount
+// that will be paid (in the atomic units of the requirements' asset).
+//
+// An Approver may block until a decision is made (for instance, while
+// waiting for a human to confirm the payment) but should return promptly
+// with ctx.Err() when the request's context is done.  Returning false or an
+// error prevents the payment from being made.
+type Approver func(ctx context.Context, u *url.URL, requirements types.PaymentRequirements, amount *big.Int) (bool, error)
+
+// ApproveBelow returns an Approver that approves any payment whose amount
+// is less than threshold without consulting next.  Larger payments are
+// approved only if next approves them.
+func ApproveBelow(threshold *big.Int, next Approver) Approver {
+	return func(ctx context.Context, u *url.URL, requirements types.PaymentRequirements, amount *big.Int) (bool, error) {
+		if amount.Cmp(threshold) < 0 {
+			return true, nil
+		}
+
+		return next(ctx, u, requirements, amount)
+	}
+}
+
+// approve consults the configured Approver (if any) and returns an error
+// if the payment may not be made.
+func (c *config) approve(ctx context.Context, u *url.URL, requirements types.PaymentRequirements, amount *big.Int) error {
+	if c.approver == nil {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ok, err := c.approver(ctx, u, requirements, new(big.Int).Set(amount))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrPaymentDenied
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package buyer_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+)
+
+func TestApprover(t *testing.T) {
+	t.Parallel()
+
+	deny := func(context.Context, *url.URL, types.PaymentRequirements, *big.Int) (bool, error) {
+		return false, nil
+	}
+
+	block := func(ctx context.Context, _ *url.URL, _ types.PaymentRequirements, _ *big.Int) (bool, error) {
+		<-ctx.Done()
+
+		return false, ctx.Err()
+	}
+
+	t.Run("passes - approved", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			actURL    string
+			actAmount *big.Int
+		)
+
+		approve := func(_ context.Context, u *url.URL, requirements types.PaymentRequirements, amount *big.Int) (bool, error) {
+			actURL = u.String()
+			actAmount = amount
+
+			return requirements.Network == "base", nil
+		}
+
+		respIn := okResponse()
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), respIn}, buyer.WithApprover(approve))
+
+		respOut, err := doRequest(t, trans, "https://example.com/joke")
+		require.NoError(t, err)
+		assert.Equal(t, respIn, respOut)
+		assert.Equal(t, "https://example.com/joke", actURL)
+		assert.Equal(t, "10000", actAmount.String())
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+	})
+
+	t.Run("passes - below threshold", func(t *testing.T) {
+		t.Parallel()
+
+		respIn := okResponse()
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), respIn}, buyer.WithApprover(buyer.ApproveBelow(big.NewInt(10001), deny)))
+
+		respOut, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+		assert.Equal(t, respIn, respOut)
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+	})
+
+	t.Run("fails - denied above threshold", func(t *testing.T) {
+		t.Parallel()
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq)}, buyer.WithApprover(buyer.ApproveBelow(big.NewInt(10000), deny)))
+
+		_, err := doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrPaymentDenied)
+	})
+
+	t.Run("fails - context cancelled while waiting", func(t *testing.T) {
+		t.Parallel()
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq)}, buyer.WithApprover(block))
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", strings.NewReader("Request body"))
+		require.NoError(t, err)
+
+		go cancel()
+
+		_, err = trans.RoundTrip(req)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,274 @@
+package buyer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/lmittmann/tint"
+)
+
+const defaultSpoolThreshold = 1 << 20
+
+// ErrBodyNotReplayable is returned when a payment is required but the
+// request's body can't be sent a second time because it's larger than the
+// size set using the WithMaxReplayableBodySize Option.
+var ErrBodyNotReplayable = errors.New("request body is too large to replay")
+
+// replayableBody provides a fresh copy of a request's body for each of the
+// round-trips that might be needed to make a payment.
+type replayableBody struct {
+	first   io.ReadCloser
+	getBody func() (io.ReadCloser, error)
+	cleanup func() error
+	sent    bool
+}
+
+// newReplayableBody prepares the body of the provided http.Request so that
+// it can be sent more than once.
+//
+// If the request provides a GetBody function, the body is streamed and
+// GetBody is used to obtain copies.  Otherwise bodies smaller than the
+// spool threshold are held in memory while larger bodies are written to a
+// temporary file.  Bodies larger than the maximum replayable size are
+// streamed and can't be replayed.  If the request's ContentLength shows
+// that the body is too large, none of it is buffered.
+func (c *config) newReplayableBody(req *http.Request) (*replayableBody, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return &replayableBody{}, nil
+	}
+
+	if req.GetBody != nil {
+		return &replayableBody{
+			first:   req.Body,
+			getBody: req.GetBody,
+		}, nil
+	}
+
+	// Bodies that are known to be too large to replay are streamed
+	// without being buffered.
+	if c.maxReplayableBody > 0 && req.ContentLength > c.maxReplayableBody {
+		return &replayableBody{
+			first: req.Body,
+		}, nil
+	}
+
+	threshold := c.spoolThreshold
+	if c.maxReplayableBody > 0 && c.maxReplayableBody < threshold {
+		threshold = c.maxReplayableBody
+	}
+
+	buf := &bytes.Buffer{}
+
+	n, err := io.Copy(buf, io.LimitReader(req.Body, threshold+1))
+	if err != nil {
+		return nil, errors.Join(err, req.Body.Close())
+	}
+
+	if n <= threshold {
+		if err := req.Body.Close(); err != nil {
+			return nil, err
+		}
+
+		data := buf.Bytes()
+
+		return newReplayableBodyFunc(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}, nil)
+	}
+
+	if c.maxReplayableBody > 0 && n > c.maxReplayableBody {
+		return streamedBody(buf, req.Body), nil
+	}
+
+	return c.spool(buf, req.Body)
+}
+
+// spool writes the buffered data and the remainder of the request body to
+// a temporary file.
+func (c *config) spool(buf *bytes.Buffer, body io.ReadCloser) (*replayableBody, error) {
+	f, err := os.CreateTemp("", "x402-buyer-body-*")
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to create request body spool: %w", err), body.Close())
+	}
+
+	spool := &spoolFile{f: f, refs: 1}
+
+	src := io.Reader(body)
+	if c.maxReplayableBody > 0 {
+		src = io.LimitReader(body, c.maxReplayableBody-int64(buf.Len())+1)
+	}
+
+	n, err := io.Copy(f, io.MultiReader(buf, src))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to spool request body: %w", err), body.Close(), spool.release())
+	}
+
+	if c.maxReplayableBody > 0 && n > c.maxReplayableBody {
+		rb := streamedBody(spool.reader(n), body)
+		rb.cleanup = spool.release
+
+		return rb, nil
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, errors.Join(err, spool.release())
+	}
+
+	c.log.Debug("Request body spooled", slog.String("path", f.Name()), slog.Int64("size", n))
+
+	return newReplayableBodyFunc(func() (io.ReadCloser, error) {
+		return spool.reader(n), nil
+	}, spool.release)
+}
+
+// spoolFile is a temporary file holding a request body.  The
+// http.RoundTripper may still be reading a body after RoundTrip returns,
+// so the file is reference counted and only removed once the
+// replayableBody and every body read from the file have been closed.
+type spoolFile struct {
+	mu   sync.Mutex
+	f    *os.File
+	refs int
+}
+
+// reader returns a body containing the first size bytes of the file.
+func (s *spoolFile) reader(size int64) io.ReadCloser {
+	s.mu.Lock()
+	s.refs++
+	s.mu.Unlock()
+
+	return &spoolReader{
+		Reader: io.NewSectionReader(s.f, 0, size),
+		spool:  s,
+	}
+}
+
+// release drops a reference to the file and removes it once no references
+// remain.
+func (s *spoolFile) release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+
+	return errors.Join(s.f.Close(), os.Remove(s.f.Name()))
+}
+
+// spoolReader is a body read from a spoolFile.  Closing it releases its
+// reference to the file.
+type spoolReader struct {
+	io.Reader
+
+	spool *spoolFile
+	once  sync.Once
+}
+
+// Close implements io.Closer.
+func (r *spoolReader) Close() error {
+	var err error
+
+	r.once.Do(func() {
+		err = r.spool.release()
+	})
+
+	return err
+}
+
+func newReplayableBodyFunc(getBody func() (io.ReadCloser, error), cleanup func() error) (*replayableBody, error) {
+	first, err := getBody()
+	if err != nil {
+		return nil, err
+	}
+
+	return &replayableBody{
+		first:   first,
+		getBody: getBody,
+		cleanup: cleanup,
+	}, nil
+}
+
+// streamedBody returns a replayableBody that sends the already read prefix
+// followed by the remainder of the body exactly once.  If the prefix is an
+// io.Closer, it's closed along with the body.
+func streamedBody(prefix io.Reader, rest io.ReadCloser) *replayableBody {
+	closer := io.Closer(rest)
+	if c, ok := prefix.(io.Closer); ok {
+		closer = closerFunc(func() error {
+			return errors.Join(rest.Close(), c.Close())
+		})
+	}
+
+	return &replayableBody{
+		first: struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.MultiReader(prefix, rest),
+			Closer: closer,
+		},
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// next returns the body that should be sent with the next round-trip or
+// nil if the request has no body.
+func (b *replayableBody) next() (io.ReadCloser, error) {
+	if b.first == nil {
+		return nil, nil
+	}
+
+	if !b.sent {
+		b.sent = true
+
+		return b.first, nil
+	}
+
+	return b.replay()
+}
+
+// replayable returns true if the body can be sent again.
+func (b *replayableBody) replayable() bool {
+	return b.first == nil || !b.sent || b.getBody != nil
+}
+
+// replay returns a fresh copy of the body.
+func (b *replayableBody) replay() (io.ReadCloser, error) {
+	if b.getBody == nil {
+		return nil, ErrBodyNotReplayable
+	}
+
+	return b.getBody()
+}
+
+// close releases any resources used to make the body replayable.  Bodies
+// that have already been sent are closed by the http.RoundTripper, so any
+// spooled copy remains available until they are.
+func (b *replayableBody) close(log *slog.Logger) {
+	if b.first != nil && !b.sent {
+		if err := b.first.Close(); err != nil {
+			log.Error("failed to close request body", tint.Err(err))
+		}
+	}
+
+	if b.cleanup == nil {
+		return
+	}
+
+	if err := b.cleanup(); err != nil {
+		log.Error("failed to remove request body spool", tint.Err(err))
+	}
+}
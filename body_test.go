@@ -0,0 +1,161 @@
+package buyer_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestReplayableBody(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		opts []buyer.Option
+	}{
+		{
+			name: "in memory",
+		},
+		{
+			name: "spooled",
+			opts: []buyer.Option{buyer.WithBodySpoolThreshold(4)},
+		},
+		{
+			name: "spooled below maximum",
+			opts: []buyer.Option{buyer.WithBodySpoolThreshold(4), buyer.WithMaxReplayableBodySize(12)},
+		},
+	} {
+		t.Run("passes - "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			respIn := okResponse()
+			trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), respIn}, tc.opts...)
+
+			// Hide the strings.Reader so that http.NewRequest doesn't
+			// provide a GetBody function.
+			req, err := http.NewRequest(http.MethodGet, "https://example.com", io.NopCloser(strings.NewReader("Request body")))
+			require.NoError(t, err)
+			require.Nil(t, req.GetBody)
+
+			respOut, err := trans.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, respIn, respOut)
+
+			t.Cleanup(func() {
+				require.NoError(t, respOut.Body.Close())
+			})
+		})
+	}
+
+	t.Run("passes - larger than maximum without payment", func(t *testing.T) {
+		t.Parallel()
+
+		respIn := okResponse()
+		trans, _ := newTestTransport(t, []*http.Response{respIn}, buyer.WithBodySpoolThreshold(4), buyer.WithMaxReplayableBodySize(8))
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", io.NopCloser(strings.NewReader("Request body")))
+		require.NoError(t, err)
+
+		respOut, err := trans.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, respIn, respOut)
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+	})
+
+	t.Run("fails - larger than maximum with payment", func(t *testing.T) {
+		t.Parallel()
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq)}, buyer.WithMaxReplayableBodySize(8))
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", io.NopCloser(strings.NewReader("Request body")))
+		require.NoError(t, err)
+
+		_, err = trans.RoundTrip(req)
+		require.ErrorIs(t, err, buyer.ErrBodyNotReplayable)
+	})
+
+	t.Run("passes - known to be larger than maximum not buffered", func(t *testing.T) {
+		t.Parallel()
+
+		signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+		require.NoError(t, err)
+
+		next := &lateReadTransport{resps: []*http.Response{okResponse()}}
+		trans, err := buyer.NewTransport(next, signer, buyer.WithBodySpoolThreshold(4), buyer.WithMaxReplayableBodySize(8))
+		require.NoError(t, err)
+
+		body := io.NopCloser(strings.NewReader("Request body"))
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", body)
+		require.NoError(t, err)
+
+		req.ContentLength = int64(len("Request body"))
+
+		respOut, err := trans.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, respOut.Body.Close())
+
+		// The request's own body is sent rather than a buffered copy.
+		require.Len(t, next.bodies, 1)
+		assert.Equal(t, body, next.bodies[0])
+		require.NoError(t, body.Close())
+	})
+
+	t.Run("passes - spooled body read after round-trip", func(t *testing.T) {
+		t.Parallel()
+
+		signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+		require.NoError(t, err)
+
+		next := &lateReadTransport{resps: []*http.Response{paymentRequiredResponse(payReq), okResponse()}}
+		trans, err := buyer.NewTransport(next, signer, buyer.WithBodySpoolThreshold(4))
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", io.NopCloser(strings.NewReader("Request body")))
+		require.NoError(t, err)
+
+		respOut, err := trans.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, respOut.Body.Close())
+
+		require.Len(t, next.bodies, 2)
+
+		for _, body := range next.bodies {
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, "Request body", string(data))
+			require.NoError(t, body.Close())
+		}
+	})
+}
+
+var _ http.RoundTripper = (*lateReadTransport)(nil)
+
+// lateReadTransport is an http.RoundTripper that returns its responses
+// without reading the request bodies, which are kept to be read after
+// RoundTrip returns (as the http.Transport might when the server responds
+// early.)
+type lateReadTransport struct {
+	resps  []*http.Response
+	bodies []io.ReadCloser
+}
+
+func (t *lateReadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.bodies = append(t.bodies, req.Body)
+
+	resp := t.resps[0]
+	t.resps = t.resps[1:]
+
+	return resp, nil
+}
@@ -0,0 +1,160 @@
+package buyer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned (wrapped in a BudgetExceededError) when
+// making a payment would exceed one of the limits configured using the
+// WithMaxPayment, WithBudget, WithHostBudget or WithAssetBudget options.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError describes the limit that would be exceeded if a
+// payment were made.  Amounts are expressed in the atomic units of the
+// asset being paid.
+type BudgetExceededError struct {
+	// Limit describes the limit that would be exceeded.
+	Limit string
+	// Requested is the amount of the payment that was refused.
+	Requested *big.Int
+	// Remaining is the amount that could still be paid under the limit.
+	Remaining *big.Int
+}
+
+// Error implements error.
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: %s: requested %s, remaining %s", ErrBudgetExceeded, e.Limit, e.Requested, e.Remaining)
+}
+
+// Is allows the BudgetExceededError to match ErrBudgetExceeded when using
+// errors.Is.
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// spend records an amount that has been paid against a limit.
+type spend struct {
+	at     time.Time
+	amount *big.Int
+}
+
+// limit caps the total amount paid for the payments it matches, either for
+// the lifetime of the Transport or over a rolling window.
+type limit struct {
+	desc    string
+	max     *big.Int
+	window  time.Duration
+	matches func(host, asset string) bool
+	spends  []*spend
+}
+
+// spent returns the total amount paid within the limit's window and
+// discards the spends that have fallen out of it.
+func (l *limit) spent(now time.Time) *big.Int {
+	if l.window > 0 {
+		l.spends = slices.DeleteFunc(l.spends, func(s *spend) bool {
+			return !s.at.After(now.Add(-l.window))
+		})
+	}
+
+	total := new(big.Int)
+	for _, s := range l.spends {
+		total.Add(total, s.amount)
+	}
+
+	return total
+}
+
+// budget enforces the spending limits configured for a Transport.
+type budget struct {
+	mu         sync.Mutex
+	now        func() time.Time
+	maxPayment *big.Int
+	limits     []*limit
+}
+
+func newBudget() *budget {
+	return &budget{
+		now: time.Now,
+	}
+}
+
+func (b *budget) addLimit(desc string, max *big.Int, window time.Duration, matches func(host, asset string) bool) {
+	if window > 0 {
+		desc = fmt.Sprintf("%s per %s", desc, window)
+	}
+
+	b.limits = append(b.limits, &limit{
+		desc:    desc,
+		max:     max,
+		window:  window,
+		matches: matches,
+	})
+}
+
+// reserve records a payment of amount to host for asset against each of
+// the matching limits or returns a BudgetExceededError if any would be
+// exceeded.  The returned function removes the reservation and should be
+// called if the payment is not made.
+func (b *budget) reserve(host, asset string, amount *big.Int) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxPayment != nil && amount.Cmp(b.maxPayment) > 0 {
+		return nil, &BudgetExceededError{
+			Limit:     "maximum payment",
+			Requested: new(big.Int).Set(amount),
+			Remaining: new(big.Int).Set(b.maxPayment),
+		}
+	}
+
+	now := b.now()
+
+	var matched []*limit
+
+	for _, l := range b.limits {
+		if !l.matches(host, asset) {
+			continue
+		}
+
+		remaining := new(big.Int).Sub(l.max, l.spent(now))
+		if amount.Cmp(remaining) > 0 {
+			if remaining.Sign() < 0 {
+				remaining.SetInt64(0)
+			}
+
+			return nil, &BudgetExceededError{
+				Limit:     l.desc,
+				Requested: new(big.Int).Set(amount),
+				Remaining: remaining,
+			}
+		}
+
+		matched = append(matched, l)
+	}
+
+	s := &spend{
+		at:     now,
+		amount: new(big.Int).Set(amount),
+	}
+
+	for _, l := range matched {
+		l.spends = append(l.spends, s)
+	}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for _, l := range matched {
+			l.spends = slices.DeleteFunc(l.spends, func(o *spend) bool {
+				return o == s
+			})
+		}
+	}, nil
+}
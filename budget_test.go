@@ -0,0 +1,120 @@
+package buyer_test
+
+import (
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+)
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		opts   []buyer.Option
+		paid   bool // whether one payment is made before the limit is reached
+		limit  string
+		remain int64
+	}{
+		{
+			name:   "maximum payment",
+			opts:   []buyer.Option{buyer.WithMaxPayment(big.NewInt(5000))},
+			paid:   false,
+			limit:  "maximum payment",
+			remain: 5000,
+		},
+		{
+			name:   "total",
+			opts:   []buyer.Option{buyer.WithBudget(big.NewInt(15000), 0)},
+			paid:   true,
+			limit:  "total",
+			remain: 5000,
+		},
+		{
+			name:   "host",
+			opts:   []buyer.Option{buyer.WithHostBudget("EXAMPLE.COM", big.NewInt(15000), time.Hour)},
+			paid:   true,
+			limit:  "host EXAMPLE.COM per 1h0m0s",
+			remain: 5000,
+		},
+		{
+			name:   "asset",
+			opts:   []buyer.Option{buyer.WithAssetBudget("0x833589fcd6edb6e08f4c7c32d4f71b54bda02913", big.NewInt(10000), 24*time.Hour)},
+			paid:   true,
+			limit:  "asset 0x833589fcd6edb6e08f4c7c32d4f71b54bda02913 per 24h0m0s",
+			remain: 0,
+		},
+	} {
+		t.Run("fails - "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), okResponse(), paymentRequiredResponse(payReq)}, tc.opts...)
+
+			if tc.paid {
+				resp, err := doRequest(t, trans, "https://example.com")
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				require.NoError(t, resp.Body.Close())
+			}
+
+			_, err := doRequest(t, trans, "https://example.com")
+			require.ErrorIs(t, err, buyer.ErrBudgetExceeded)
+
+			var budgetErr *buyer.BudgetExceededError
+			require.ErrorAs(t, err, &budgetErr)
+			assert.Equal(t, tc.limit, budgetErr.Limit)
+			assert.Equal(t, "10000", budgetErr.Requested.String())
+			assert.Equal(t, big.NewInt(tc.remain).String(), budgetErr.Remaining.String())
+		})
+	}
+
+	t.Run("passes - window expired", func(t *testing.T) {
+		t.Parallel()
+
+		const window = 200 * time.Millisecond
+
+		trans, _ := newTestTransport(t, []*http.Response{
+			paymentRequiredResponse(payReq), okResponse(),
+			paymentRequiredResponse(payReq),
+			paymentRequiredResponse(payReq), okResponse(),
+		}, buyer.WithHostBudget("example.com", big.NewInt(10000), window))
+
+		resp, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		_, err = doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrBudgetExceeded)
+
+		time.Sleep(window + 50*time.Millisecond)
+
+		resp, err = doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	})
+
+	t.Run("passes - other host", func(t *testing.T) {
+		t.Parallel()
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), okResponse()}, buyer.WithHostBudget("example.org", big.NewInt(0), 0))
+
+		resp, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	})
+
+	t.Run("fails - negative budget", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := buyer.NewTransport(http.DefaultTransport, nil, buyer.WithBudget(big.NewInt(-1), time.Hour))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,90 @@
+package buyer
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	offer   offer
+	expires time.Time
+}
+
+// requirementsCache remembers the offer that was last paid for each
+// resource so that later requests can include a payment
+// without first waiting for a 402 Payment Required response.
+//
+// A nil *requirementsCache is valid and never contains any requirements.
+type requirementsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cacheEntry
+}
+
+func newRequirementsCache(ttl time.Duration) *requirementsCache {
+	return &requirementsCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// cacheKey identifies the resource requested by the http.Request.
+func cacheKey(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return req.Method + " " + u.String()
+}
+
+func (c *requirementsCache) get(req *http.Request) (offer, bool) {
+	if c == nil {
+		return offer{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(req)
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return offer{}, false
+	}
+
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+
+		return offer{}, false
+	}
+
+	return entry.offer, true
+}
+
+func (c *requirementsCache) put(req *http.Request, o offer) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cacheKey(req)] = cacheEntry{
+		offer:   o,
+		expires: c.now().Add(c.ttl),
+	}
+}
+
+func (c *requirementsCache) invalidate(req *http.Request) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, cacheKey(req))
+}
@@ -0,0 +1,75 @@
+package buyer_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+)
+
+func TestRequirementsCache(t *testing.T) {
+	t.Parallel()
+
+	paidRequest := func(t *testing.T, trans http.RoundTripper, url string) {
+		t.Helper()
+
+		resp, err := doRequest(t, trans, url)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, paid := buyer.PaymentFromResponse(resp)
+		assert.True(t, paid)
+
+		require.NoError(t, resp.Body.Close())
+	}
+
+	t.Run("passes - preemptive payment", func(t *testing.T) {
+		t.Parallel()
+
+		trans, next := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), okResponse(), okResponse()}, buyer.WithRequirementsCache(time.Hour))
+
+		paidRequest(t, trans, "https://example.com/joke")
+		paidRequest(t, trans, "https://example.com/joke#punchline")
+
+		require.Len(t, next.headers, 3)
+		assert.Empty(t, next.headers[0].Get("X-Payment"))
+		assert.NotEmpty(t, next.headers[1].Get("X-Payment"))
+		assert.NotEmpty(t, next.headers[2].Get("X-Payment"))
+	})
+
+	t.Run("passes - different resource", func(t *testing.T) {
+		t.Parallel()
+
+		trans, next := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), okResponse(), paymentRequiredResponse(payReq), okResponse()}, buyer.WithRequirementsCache(time.Hour))
+
+		paidRequest(t, trans, "https://example.com/joke")
+		paidRequest(t, trans, "https://example.com/riddle")
+
+		require.Len(t, next.headers, 4)
+		assert.Empty(t, next.headers[2].Get("X-Payment"))
+	})
+
+	t.Run("passes - preemptive payment not accepted", func(t *testing.T) {
+		t.Parallel()
+
+		trans, next := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), okResponse(), paymentRequiredResponse(payReq), okResponse()}, buyer.WithRequirementsCache(time.Hour))
+
+		paidRequest(t, trans, "https://example.com/joke")
+		paidRequest(t, trans, "https://example.com/joke")
+
+		require.Len(t, next.headers, 4)
+		assert.NotEmpty(t, next.headers[2].Get("X-Payment"))
+		assert.NotEmpty(t, next.headers[3].Get("X-Payment"))
+	})
+
+	t.Run("fails - invalid time-to-live", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := buyer.NewTransport(http.DefaultTransport, nil, buyer.WithRequirementsCache(0))
+		require.Error(t, err)
+	})
+}
@@ -1,11 +1,15 @@
 package buyer
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"fmt"
 	"net/http"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
 
 	"github.com/selesy/x402-buyer/internal/signer"
 	"github.com/selesy/x402-buyer/pkg/api"
@@ -22,6 +26,30 @@ func ClientForKeyStore(ks *keystore.KeyStore, acct accounts.Account, pass []byte
 	return ClientForSigner(signer, opts...)
 }
 
+// ClientForWallet returns an http.Client capable of making payments using
+// cryptocurrency from an account of a go-ethereum accounts.Wallet (such as
+// a keystore or hardware wallet.)  The account must already be unlocked or
+// the wallet must be able to sign without a passphrase.
+func ClientForWallet(wallet accounts.Wallet, acct accounts.Account, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewWalletSigner(wallet, acct, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// ClientForWalletWithPassphrase is like ClientForWallet except that the
+// passphrase is used to unlock the account for each payment.
+func ClientForWalletWithPassphrase(wallet accounts.Wallet, acct accounts.Account, pass []byte, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewWalletSigner(wallet, acct, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
 // ClientForPrivateKey returns an http.Client capable of making payments
 // using cryptocurrency from the Ethereum account associated with the provided
 // ECDSA private key (which is expected to be using the Ethereum secp256k1
@@ -57,6 +85,97 @@ func ClientForPrivateKeyHexFromEnv(name string, opts ...Option) (*http.Client, e
 	return ClientForSigner(signer, opts...)
 }
 
+// ClientForMnemonic returns an http.Client capable of making payments
+// using cryptocurrency from the account at the BIP-32 derivationPath (e.g.
+// m/44'/60'/0'/0/0) of the hierarchical deterministic wallet created from
+// the BIP-39 mnemonic and passphrase.  If derivationPath is empty, the
+// wallet's first Ethereum account is used.
+func ClientForMnemonic(mnemonic, passphrase, derivationPath string, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewHDSigner(mnemonic, passphrase, derivationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// EthereumDerivationPath returns the standard Ethereum derivation path
+// (m/44'/60'/0'/0/index) of the HD wallet account at index.  Using these
+// paths with ClientForMnemonic allows several accounts to be provisioned
+// from a single mnemonic.
+func EthereumDerivationPath(index uint32) string {
+	return fmt.Sprintf("%s/%d", accounts.DefaultRootDerivationPath, index)
+}
+
+// ClientForExternalSigner returns an http.Client capable of making payments
+// using cryptocurrency from an account managed by a Clef-compatible external
+// signer.  The endpoint is either an HTTP(S) URL or the path of the external
+// signer's IPC socket.  If address is the zero address, the first account
+// listed by the external signer is used.  The context bounds connecting
+// to the external signer and listing its accounts (which Clef asks its user
+// to approve) but not the payments that are later made.
+//
+// Private keys never enter the buyer's process and the external signer is
+// sent the full EIP-712 typed data of each payment so that it can show its
+// user what's being authorized.
+func ClientForExternalSigner(ctx context.Context, endpoint string, address common.Address, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewExternalSigner(ctx, endpoint, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// ClientForEd25519PrivateKey returns an http.Client capable of making
+// payments using cryptocurrency from the Solana account associated with
+// the provided Ed25519 private key.
+func ClientForEd25519PrivateKey(priv ed25519.PrivateKey, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewEd25519Signer(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// ClientForSolanaKeypairFile is like ClientForEd25519PrivateKey except
+// that the private key is read from a keypair file written by the Solana
+// CLI (e.g. ~/.config/solana/id.json.)
+func ClientForSolanaKeypairFile(path string, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewEd25519SignerFromKeypairFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// ClientForSmartWallet returns an http.Client capable of making payments
+// using cryptocurrency from a deployed smart-contract wallet.  Payments are
+// signed by the wallet's owner and validated by the wallet using EIP-1271.
+func ClientForSmartWallet(wallet common.Address, owner api.Signer, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewSmartWalletSigner(wallet, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// ClientForCounterfactualWallet is like ClientForSmartWallet except that
+// the wallet hasn't been deployed yet.  Signatures are wrapped as described
+// by ERC-6492 so that the wallet is deployed by calling the factory with
+// the factoryCalldata before the signature is validated.
+func ClientForCounterfactualWallet(wallet common.Address, owner api.Signer, factory common.Address, factoryCalldata []byte, opts ...Option) (*http.Client, error) {
+	signer, err := signer.NewCounterfactualWalletSigner(wallet, owner, factory, factoryCalldata)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
 // ClientForSigner returns an http.Client capable of making x402 paybments
 // using the provided api.Signer.
 func ClientForSigner(signer api.Signer, opts ...Option) (*http.Client, error) {
@@ -65,7 +184,14 @@ func ClientForSigner(signer api.Signer, opts ...Option) (*http.Client, error) {
 		return nil, err
 	}
 
-	cfg.client.Transport = newTransport(cfg.client.Transport, signer, cfg)
+	transport, err := newTransport(cfg.client.Transport, signer, cfg)
+	if err != nil {
+		cfg.close()
+
+		return nil, err
+	}
+
+	cfg.client.Transport = transport
 
 	return cfg.client, nil
 }
@@ -1,17 +1,21 @@
 package buyer_test
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"os"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	buyer "github.com/selesy/x402-buyer"
 	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
 	"github.com/selesy/x402-buyer/pkg/api/apitest"
 )
 
@@ -50,6 +54,85 @@ func TestClientFromPrivateKeyHexFromEnv(t *testing.T) {
 	// TODO: yes we built a client but is it working?
 }
 
+func TestClientForWallet(t *testing.T) {
+	t.Parallel()
+
+	wallet, acct := apitest.Wallet(t)
+
+	cl, err := buyer.ClientForWallet(wallet, acct)
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+
+	cl, err = buyer.ClientForWalletWithPassphrase(wallet, acct, []byte(apitest.Passphrase))
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+}
+
+func TestClientForSmartWallet(t *testing.T) {
+	t.Parallel()
+
+	owner, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	wallet := common.HexToAddress("0xCA11bde05977b3631167028862bE2a173976CA11")
+
+	cl, err := buyer.ClientForSmartWallet(wallet, owner)
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+
+	cl, err = buyer.ClientForCounterfactualWallet(wallet, owner, common.HexToAddress("0x0BA5ED0c6AA8c49038F819E587E2633c4A9F428a"), []byte{0x3f, 0xfb, 0xa3, 0x6f})
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+}
+
+func TestClientForMnemonic(t *testing.T) {
+	t.Parallel()
+
+	const mnemonic = "test test test test test test test test test test test junk"
+
+	assert.Equal(t, "m/44'/60'/0'/0/3", buyer.EthereumDerivationPath(3))
+
+	cl, err := buyer.ClientForMnemonic(mnemonic, "", buyer.EthereumDerivationPath(3))
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+
+	_, err = buyer.ClientForMnemonic("test test test", "", "")
+	require.ErrorIs(t, err, signer.ErrInvalidMnemonic)
+}
+
+func TestClientForExternalSigner(t *testing.T) {
+	t.Parallel()
+
+	srv := apitest.NewExternalSigner(t)
+
+	cl, err := buyer.ClientForExternalSigner(t.Context(), srv.URL, common.Address{})
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+
+	_, err = buyer.ClientForExternalSigner(t.Context(), srv.URL, common.HexToAddress("0x60ac86571E55F9735F00cE9e28361d203977B260"))
+	require.ErrorIs(t, err, signer.ErrExternalAccountNotFound)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err = buyer.ClientForExternalSigner(ctx, srv.URL, common.Address{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClientForEd25519PrivateKey(t *testing.T) {
+	t.Parallel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cl, err := buyer.ClientForEd25519PrivateKey(priv, buyer.WithSolanaRPC("solana-devnet", "http://localhost:8899"))
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+
+	_, err = buyer.ClientForEd25519PrivateKey(priv, buyer.WithSolanaRPC("solana-testnet", "http://localhost:8899"))
+	require.ErrorIs(t, err, api.ErrUnknownNetwork)
+}
+
 func TestClientForSigner(t *testing.T) {
 	t.Parallel()
 
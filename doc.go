@@ -1,16 +1,46 @@
 // Package buyer produces http.Client that can make [x402] payments for HTTP
 // content and services.
 //
+// Both versions 1 and 2 of the x402 protocol are supported.  The version
+// advertised by the seller in its 402 Payment Required response is used
+// to make the payment.
+//
+// The key used to sign payments can be chosen using configuration by
+// passing a URI (e.g. env:X402_BUYER_PRIVATE_KEY or
+// keystore:/path/to/keystore?password-file=/path/to/password) to
+// ClientForSignerURI.  Other modules can add their own key sources using
+// RegisterSignerScheme.
+//
 // It is anticipated that this software will commonly be used to allow
 // AI agents to pay for the services they need.  When allowing automated
 // payments on your behalf, care should be taken to limit your financial
-// exposure.
+// exposure.  The WithMaxPayment, WithBudget, WithHostBudget and
+// WithAssetBudget options cap the amounts that will be paid and are checked
+// before each payment is signed.  Payments using the "upto" scheme, where
+// the seller charges for actual usage, count their authorized maximum
+// against these limits.  When a network's JSON-RPC endpoint is provided
+// using the WithRPC option, payments that the buyer's balance can't cover
+// fail with ErrInsufficientFunds rather than being sent to the seller.
+// Transport.Close closes the connections to these endpoints.
 //
 // Defaults
 //
 //   - If the WithClient option is not specified, the http.DefaultClient
 //     is used with the http.DefaultTransport.
 //   - If the WithLogger Option is not specified, a No-Op logger is used.
+//   - If the WithSelector Option is not specified, the first payment
+//     requirement that can be paid is selected.
+//   - When the api.Signer is an api.EVMSigner, a payer for the "exact"
+//     scheme on EVM networks is registered after any provided using the
+//     WithPayer Option.  A payer for the "upto" scheme is only registered
+//     when the WithUpto Option is provided.  They only pay with the
+//     well-known USDC deployments unless other tokens are registered using
+//     the WithToken Option.
+//   - When the api.Signer is an api.SVMSigner, a payer for the "exact"
+//     scheme on Solana is registered.  It uses the clusters' public
+//     JSON-RPC endpoints unless others are set using the WithSolanaRPC
+//     Option and only pays with the USDC mints unless others are
+//     registered using the WithSolanaMint Option.
 //
 // [x402]: https://x402.org
 package buyer
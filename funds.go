@@ -0,0 +1,165 @@
+package buyer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/lmittmann/tint"
+
+	"github.com/selesy/x402-buyer/internal/eip712"
+	"github.com/selesy/x402-buyer/internal/erc20"
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// ErrInsufficientFunds is returned (wrapped in an InsufficientFundsError)
+// when the buyer's balance or allowance can't cover a payment.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// InsufficientFundsError describes why the buyer's account can't cover a
+// payment.  Amounts are expressed in the atomic units of the asset being
+// paid.
+type InsufficientFundsError struct {
+	// Asset is the address of the token being paid.
+	Asset string
+	// Required is the amount of the payment.
+	Required *big.Int
+	// Balance is the buyer's balance of the token.
+	Balance *big.Int
+	// Allowance is the amount that the Permit2 contract may transfer on
+	// the buyer's behalf or nil if the payment doesn't use Permit2.
+	Allowance *big.Int
+}
+
+// Error implements error.
+func (e *InsufficientFundsError) Error() string {
+	if e.Balance.Cmp(e.Required) < 0 {
+		return fmt.Sprintf("%s: balance of %s is %s, required %s", ErrInsufficientFunds, e.Asset, e.Balance, e.Required)
+	}
+
+	return fmt.Sprintf("%s: Permit2 allowance of %s is %s, required %s", ErrInsufficientFunds, e.Asset, e.Allowance, e.Required)
+}
+
+// Is allows the InsufficientFundsError to match ErrInsufficientFunds when
+// using errors.Is.
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == ErrInsufficientFunds
+}
+
+// caller returns the ContractCaller registered for the chain (if any.)
+func (c *config) caller(chainID *big.Int) (ethereum.ContractCaller, bool) {
+	for name, caller := range c.callers {
+		network, err := c.networks.Lookup(name)
+		if err == nil && network.ChainID.Cmp(chainID) == 0 {
+			return caller, true
+		}
+	}
+
+	return nil, false
+}
+
+// checkFunds returns an error if the signer's balance of the asset (or
+// its Permit2 allowance when required) is less than amount.  The check is
+// only made for EVM payments on networks with a registered ContractCaller.
+// Since the check is only a courtesy to avoid payments that would be
+// rejected, a failure to read the balance doesn't prevent the payment.
+func (c *config) checkFunds(ctx context.Context, signer api.Signer, requirements types.PaymentRequirements, amount *big.Int) error {
+	evmSigner, ok := signer.(api.EVMSigner)
+	if !ok || !common.IsHexAddress(requirements.Asset) {
+		return nil
+	}
+
+	network, err := c.networks.Lookup(requirements.Network)
+	if err != nil {
+		return nil
+	}
+
+	caller, ok := c.caller(network.ChainID)
+	if !ok {
+		return nil
+	}
+
+	token := common.HexToAddress(requirements.Asset)
+	owner := evmSigner.Address()
+
+	balance, err := erc20.BalanceOf(ctx, caller, token, owner)
+	if err != nil {
+		c.log.Warn("Unable to check balance", slog.String("asset", requirements.Asset), tint.Err(err))
+
+		return nil
+	}
+
+	if balance.Cmp(amount) < 0 {
+		return &InsufficientFundsError{
+			Asset:    requirements.Asset,
+			Required: amount,
+			Balance:  balance,
+		}
+	}
+
+	if !usesPermit2(requirements) {
+		return nil
+	}
+
+	allowance, err := erc20.Allowance(ctx, caller, token, owner, eip712.Permit2Address)
+	if err != nil {
+		c.log.Warn("Unable to check Permit2 allowance", slog.String("asset", requirements.Asset), tint.Err(err))
+
+		return nil
+	}
+
+	if allowance.Cmp(amount) < 0 {
+		return &InsufficientFundsError{
+			Asset:     requirements.Asset,
+			Required:  amount,
+			Balance:   balance,
+			Allowance: allowance,
+		}
+	}
+
+	return nil
+}
+
+// permitNonce implements api.PermitNonceFunc using the ContractCaller
+// registered for the token's chain.
+func (c *config) permitNonce(ctx context.Context, token api.Token, owner common.Address) (*big.Int, error) {
+	caller, ok := c.caller(token.ChainID)
+	if !ok {
+		return nil, api.ErrPermitNonceUnavailable
+	}
+
+	return erc20.Nonces(ctx, caller, token.Address, owner)
+}
+
+// nonceUsed implements nonce.Checker using the ERC-3009 authorizationState
+// method of the token.  Permit2 nonces aren't checked.
+func (c *config) nonceUsed(ctx context.Context, scope api.NonceScope, nonce []byte) (bool, error) {
+	if scope.Contract == eip712.Permit2Address {
+		return false, nil
+	}
+
+	caller, ok := c.caller(scope.ChainID)
+	if !ok {
+		return false, nil
+	}
+
+	return erc20.AuthorizationState(ctx, caller, scope.Contract, scope.Account, common.BytesToHash(nonce))
+}
+
+// usesPermit2 returns true if the payment's tokens are transferred by the
+// Permit2 contract, which requires the buyer to have approved it.
+func usesPermit2(requirements types.PaymentRequirements) bool {
+	if requirements.Scheme == string(api.SchemeUpto) {
+		return true
+	}
+
+	extra, err := exact.ParseExtra(requirements)
+
+	return err == nil && extra.AssetTransferMethod == exact.TransferMethodPermit2
+}
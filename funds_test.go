@@ -0,0 +1,179 @@
+package buyer_test
+
+import (
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+// tokenCode is the runtime bytecode of a minimal token contract whose
+// balanceOf(owner), allowance(owner, spender) and nonces(owner) methods
+// return the values stored at slots owner, owner+1 and owner+2.  Its
+// authorizationState(authorizer, nonce) method returns the value stored at
+// slot nonce.  The allowance's spender and the authorizer are ignored.
+var tokenCode = hexutil.MustDecode("0x60003560e01c806370a08231146032578063dd62ed3e14603f5780637ecebe0014604f578063e94a010214605f57600080fd5b6004355460005260206000f35b6004356001015460005260206000f35b6004356002015460005260206000f35b6024355460005260206000f3")
+
+// simulatedToken is the token deployed by newSimulatedBackend.
+var simulatedToken = api.Token{
+	ChainID:  big.NewInt(1337),
+	Address:  common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"),
+	Name:     "Simulated USD",
+	Version:  "1",
+	Decimals: 6,
+}
+
+// newSimulatedBackend returns a simulated chain where the owner has the
+// balance, Permit2 allowance and EIP-2612 nonce and the ERC-3009 nonces
+// have been used.
+func newSimulatedBackend(t *testing.T, owner common.Address, balance, allowance, nonce int64, used ...common.Hash) simulated.Client {
+	t.Helper()
+
+	slot := new(big.Int).SetBytes(owner.Bytes())
+
+	storage := map[common.Hash]common.Hash{
+		common.BigToHash(slot): common.BigToHash(big.NewInt(balance)),
+		common.BigToHash(new(big.Int).Add(slot, big.NewInt(1))): common.BigToHash(big.NewInt(allowance)),
+		common.BigToHash(new(big.Int).Add(slot, big.NewInt(2))): common.BigToHash(big.NewInt(nonce)),
+	}
+
+	for _, nonce := range used {
+		storage[nonce] = common.BigToHash(big.NewInt(1))
+	}
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		simulatedToken.Address: {
+			Code:    tokenCode,
+			Balance: big.NewInt(0),
+			Storage: storage,
+		},
+	})
+
+	t.Cleanup(func() {
+		require.NoError(t, backend.Close())
+	})
+
+	return backend.Client()
+}
+
+func TestCheckFunds(t *testing.T) {
+	t.Parallel()
+
+	const (
+		exactReq  = `{"accepts":[{"scheme":"exact","network":"simulated","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x5FbDB2315678afecb367f032d93F642f64180aa3"}],"error":"X-PAYMENT header is required","x402Version":1}`
+		permitReq = `{"accepts":[{"scheme":"exact","network":"simulated","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x5FbDB2315678afecb367f032d93F642f64180aa3","extra":{"assetTransferMethod":"permit"}}],"error":"X-PAYMENT header is required","x402Version":1}`
+		uptoReq   = `{"accepts":[{"scheme":"upto","network":"simulated","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x5FbDB2315678afecb367f032d93F642f64180aa3"}],"error":"X-PAYMENT header is required","x402Version":1}`
+	)
+
+	token := simulatedToken
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	newBackend := func(t *testing.T, balance, allowance, nonce int64) simulated.Client {
+		t.Helper()
+
+		return newSimulatedBackend(t, signer.Address(), balance, allowance, nonce)
+	}
+
+	roundTrip := func(t *testing.T, client simulated.Client, payReq string, resps ...*http.Response) (*http.Response, error) {
+		t.Helper()
+
+		resps = append([]*http.Response{{
+			StatusCode: http.StatusPaymentRequired,
+			Body:       io.NopCloser(strings.NewReader(payReq)),
+		}}, resps...)
+
+		trans, err := buyer.NewTransport(
+			newMockTransport(t, resps...),
+			signer,
+			buyer.WithNetwork("simulated", 1337),
+			buyer.WithToken(token),
+			buyer.WithContractCaller("simulated", client),
+			buyer.WithUpto(),
+		)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
+		require.NoError(t, err)
+
+		return trans.RoundTrip(req)
+	}
+
+	paid := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("Response body")),
+		}
+	}
+
+	t.Run("passes - sufficient balance", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := roundTrip(t, newBackend(t, 10000, 0, 0), exactReq, paid())
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("passes - permit nonce read from token", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := roundTrip(t, newBackend(t, 10000, 0, 7), permitReq, paid())
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		payment, ok := buyer.PaymentFromResponse(resp)
+		require.True(t, ok)
+
+		payload, ok := payment.Payload.Permit()
+		require.True(t, ok)
+		assert.Equal(t, "7", payload.Authorization.Nonce)
+	})
+
+	t.Run("fails - insufficient balance", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := roundTrip(t, newBackend(t, 9999, 0, 0), exactReq)
+		require.ErrorIs(t, err, buyer.ErrInsufficientFunds)
+
+		var fundsErr *buyer.InsufficientFundsError
+
+		require.ErrorAs(t, err, &fundsErr)
+		assert.Equal(t, "9999", fundsErr.Balance.String())
+		assert.Nil(t, fundsErr.Allowance)
+	})
+
+	t.Run("fails - insufficient Permit2 allowance", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := roundTrip(t, newBackend(t, 10000, 5000, 0), uptoReq)
+		require.ErrorIs(t, err, buyer.ErrInsufficientFunds)
+
+		var fundsErr *buyer.InsufficientFundsError
+
+		require.ErrorAs(t, err, &fundsErr)
+		require.NotNil(t, fundsErr.Allowance)
+		assert.Equal(t, "5000", fundsErr.Allowance.String())
+	})
+
+	t.Run("fails - unknown network", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := buyer.NewTransport(http.DefaultTransport, signer, buyer.WithContractCaller("unknown", newBackend(t, 0, 0, 0)))
+		require.ErrorIs(t, err, api.ErrUnknownNetwork)
+	})
+}
@@ -0,0 +1,92 @@
+// Package eip712 signs the EIP-712 typed data used to authorize payments
+// on EVM-compatible networks.
+package eip712
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// Sign hashes the typedData as described by EIP-712 and returns the
+// signer's hex-encoded signature of the hash in the form expected by the
+// verifying contract.  If the signer is an api.TypedDataSigner, it's given
+// the typedData itself rather than the hash.
+func Sign(ctx context.Context, signer api.EVMSigner, typedData apitypes.TypedData, log *slog.Logger) (string, error) {
+	hash, data, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debug("EIP-712 hash", slog.String("type", typedData.PrimaryType), slog.String("hex", hexutil.Encode(hash)))
+	log.Debug("EIP-712 message", slog.String("type", typedData.PrimaryType), slog.String("hex", hexutil.Encode([]byte(data))))
+
+	if tds, ok := signer.(api.TypedDataSigner); ok {
+		return signTypedData(ctx, tds, typedData, hash, log)
+	}
+
+	sig, err := api.SignContext(ctx, signer, hash)
+	if err != nil {
+		return "", err
+	}
+
+	if api.IsContractSigner(signer) {
+		// Smart-contract wallet signatures are validated by the wallet
+		// (EIP-1271) and don't recover to its address.
+		return hexutil.Encode(sig), nil
+	}
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debug("Recovered address", slog.String("hex", crypto.PubkeyToAddress(*pubKey).Hex()))
+
+	sig[64] += 27
+
+	log.Debug("Signature", slog.String("hex", hex.EncodeToString(sig)))
+
+	return hexutil.Encode(sig), nil
+}
+
+// signTypedData has the signer sign the full typedData and checks that the
+// signature recovers to the signer's address.
+func signTypedData(ctx context.Context, signer api.TypedDataSigner, typedData apitypes.TypedData, hash []byte, log *slog.Logger) (string, error) {
+	sig, err := signer.SignTypedData(ctx, typedData)
+	if err != nil {
+		return "", err
+	}
+
+	if api.IsContractSigner(signer) {
+		return hexutil.Encode(sig), nil
+	}
+
+	if len(sig) != crypto.SignatureLength || sig[64] < 27 {
+		return "", fmt.Errorf("invalid typed data signature: %s", hexutil.Encode(sig))
+	}
+
+	recoverable := bytes.Clone(sig)
+	recoverable[64] -= 27
+
+	pubKey, err := crypto.SigToPub(hash, recoverable)
+	if err != nil {
+		return "", err
+	}
+
+	if address := crypto.PubkeyToAddress(*pubKey); address != signer.Address() {
+		return "", fmt.Errorf("typed data signature recovers to %s rather than %s", address.Hex(), signer.Address().Hex())
+	}
+
+	log.Debug("Signature", slog.String("hex", hex.EncodeToString(sig)))
+
+	return hexutil.Encode(sig), nil
+}
@@ -0,0 +1,91 @@
+package eip712
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// Permit2Address is the address of the Uniswap Permit2 contract, which is
+// deployed at the same address on every EVM-compatible network.
+var Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// Permit returns the EIP-2612 Permit typed data that allows the spender to
+// transfer the value of the token from the owner.
+func Permit(token api.Token, authorization *api.PermitAuthorization) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"Permit": []apitypes.Type{
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain: apitypes.TypedDataDomain{
+			Name:              token.Name,
+			Version:           token.Version,
+			ChainId:           (*math.HexOrDecimal256)(token.ChainID),
+			VerifyingContract: token.Address.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":    authorization.Owner,
+			"spender":  authorization.Spender,
+			"value":    authorization.Value,
+			"nonce":    authorization.Nonce,
+			"deadline": authorization.Deadline,
+		},
+	}
+}
+
+// PermitTransferFrom returns the Permit2 PermitTransferFrom typed data
+// that allows the spender to transfer up to the permitted amount of the
+// token from the signer.
+func PermitTransferFrom(chainID *big.Int, authorization *api.Permit2Authorization) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"PermitTransferFrom": []apitypes.Type{
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"TokenPermissions": []apitypes.Type{
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+			"EIP712Domain": []apitypes.Type{
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: Permit2Address.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"permitted": apitypes.TypedDataMessage{
+				"token":  authorization.Permitted.Token,
+				"amount": authorization.Permitted.Amount,
+			},
+			"spender":  authorization.Spender,
+			"nonce":    authorization.Nonce,
+			"deadline": authorization.Deadline,
+		},
+	}
+}
@@ -0,0 +1,85 @@
+// Package erc20 reads the state of ERC-20 token contracts.
+package erc20
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// tokenABI includes the read-only methods needed to check whether a buyer
+// can pay with a token.  The authorizationState method is defined by
+// ERC-3009 and the nonces method is defined by EIP-2612.
+var tokenABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"authorizationState","stateMutability":"view","inputs":[{"name":"authorizer","type":"address"},{"name":"nonce","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"nonces","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+	]`))
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}()
+
+// BalanceOf returns the account's balance of the token.
+func BalanceOf(ctx context.Context, caller ethereum.ContractCaller, token, account common.Address) (*big.Int, error) {
+	return call(ctx, caller, token, "balanceOf", account)
+}
+
+// Allowance returns the amount of the owner's tokens that the spender is
+// allowed to transfer.
+func Allowance(ctx context.Context, caller ethereum.ContractCaller, token, owner, spender common.Address) (*big.Int, error) {
+	return call(ctx, caller, token, "allowance", owner, spender)
+}
+
+// Nonces returns the owner's current EIP-2612 permit nonce.
+func Nonces(ctx context.Context, caller ethereum.ContractCaller, token, owner common.Address) (*big.Int, error) {
+	return call(ctx, caller, token, "nonces", owner)
+}
+
+// AuthorizationState returns true if the authorizer's ERC-3009 nonce has
+// been used (or canceled.)
+func AuthorizationState(ctx context.Context, caller ethereum.ContractCaller, token, authorizer common.Address, nonce [32]byte) (bool, error) {
+	results, err := callContract(ctx, caller, token, "authorizationState", authorizer, nonce)
+	if err != nil {
+		return false, err
+	}
+
+	return results[0].(bool), nil
+}
+
+func call(ctx context.Context, caller ethereum.ContractCaller, token common.Address, method string, args ...any) (*big.Int, error) {
+	results, err := callContract(ctx, caller, token, method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0].(*big.Int), nil
+}
+
+func callContract(ctx context.Context, caller ethereum.ContractCaller, token common.Address, method string, args ...any) ([]any, error) {
+	data, err := tokenABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := caller.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s on %s: %w", method, token.Hex(), err)
+	}
+
+	results, err := tokenABI.Unpack(method, out)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s result from %s: %w", method, token.Hex(), err)
+	}
+
+	return results, nil
+}
@@ -1,9 +1,7 @@
 package evm
 
 import (
-	"encoding/hex"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,75 +9,139 @@ import (
 	"time"
 
 	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 
+	"github.com/selesy/x402-buyer/internal/eip712"
+	"github.com/selesy/x402-buyer/internal/exact"
 	"github.com/selesy/x402-buyer/pkg/api"
 )
 
-var _ api.Payer = (*ExactEvm)(nil)
+var (
+	_ api.PayloadPayer = (*ExactEvm)(nil)
+	_ api.NetworkPayer = (*ExactEvm)(nil)
+	_ api.Validator    = (*ExactEvm)(nil)
+)
 
 // ExactEvm is a payer.Payer that handles payment requests on EVM-compatible
 // networks for the "exact" scheme.
 type ExactEvm struct {
-	signer    api.EVMSigner
-	nowFunc   api.NowFunc
-	nonceFunc api.NonceFunc
-	log       *slog.Logger
+	signer   api.EVMSigner
+	networks *api.Networks
+	tokens   *exact.Tokens
+	options  *api.Options
+	log      *slog.Logger
 }
 
-func NewExactEvm(signer api.Signer, nowFunc api.NowFunc, nonceFunc api.NonceFunc, log *slog.Logger) (*ExactEvm, error) {
+func NewExactEvm(signer api.Signer, networks *api.Networks, tokens *exact.Tokens, log *slog.Logger, opts ...api.Option) (*ExactEvm, error) {
 	s, ok := signer.(api.EVMSigner)
 	if !ok {
-		return nil, errors.New("the Exact EVM scheme requires an EVM signer")
+		return nil, fmt.Errorf("%w: the Exact EVM scheme requires an EVM signer", api.ErrUnsupportedSigner)
+	}
+
+	options, err := api.NewOptions(opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ExactEvm{
-		signer:    s,
-		nowFunc:   nowFunc,
-		nonceFunc: nonceFunc,
-		log:       log,
+		signer:   s,
+		networks: networks,
+		tokens:   tokens,
+		options:  options,
+		log:      log,
 	}, nil
 }
 
-// Pay implements payer.Pay.
+// Pay implements api.Payer.
 func (e *ExactEvm) Pay(requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	return e.PayContext(context.Background(), requirements)
+}
+
+// PayContext implements api.ContextPayer.
+func (e *ExactEvm) PayContext(ctx context.Context, requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	payment, err := e.PayPayload(ctx, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment.Types()
+}
+
+// PayPayload implements api.PayloadPayer.
+func (e *ExactEvm) PayPayload(ctx context.Context, requirements types.PaymentRequirements) (*api.PaymentPayload, error) {
+	if err := e.Validate(requirements); err != nil {
+		return nil, err
+	}
+
 	switch requirements.Scheme {
 	case "exact":
-		return e.createPaymentExactEvm(requirements)
+		return e.createPaymentExactEvm(ctx, requirements)
 	default:
 		return nil, fmt.Errorf("unknown payment scheme : %w, %s", http.ErrNotSupported, requirements.Scheme)
 	}
 }
 
+// Supports implements api.NetworkPayer.
+func (e *ExactEvm) Supports(network string) bool {
+	_, err := e.networks.Lookup(network)
+
+	return err == nil
+}
+
+// Validate implements api.Validator.
+func (e *ExactEvm) Validate(requirements types.PaymentRequirements) error {
+	var verr api.ValidationError
+
+	extra := exact.ValidateRequirements(&verr, e.networks, requirements)
+
+	if requirements.Scheme != "" && requirements.Scheme != string(api.SchemeExact) {
+		verr.Add("scheme", "%q is not supported", requirements.Scheme)
+	}
+
+	if extra != nil {
+		switch method, _ := extra["assetTransferMethod"].(string); method {
+		case "", exact.TransferMethodEIP3009, exact.TransferMethodPermit, exact.TransferMethodPermit2:
+		default:
+			verr.Add("extra.assetTransferMethod", "%q is not supported", method)
+		}
+	}
+
+	return verr.Err()
+}
+
 // Scheme implements payer.Pay.
 func (e *ExactEvm) Scheme() api.Scheme {
 	return api.SchemeExact
 }
 
-func (e *ExactEvm) createPaymentExactEvm(requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
-	payload, err := e.preparePaymentHeader(requirements)
+func (e *ExactEvm) createPaymentExactEvm(ctx context.Context, requirements types.PaymentRequirements) (*api.PaymentPayload, error) {
+	extra, err := exact.ParseExtra(requirements)
 	if err != nil {
 		return nil, err
 	}
 
-	var extra map[string]any
+	switch extra.AssetTransferMethod {
+	case exact.TransferMethodPermit:
+		return e.createPaymentPermit(ctx, requirements, extra)
+	case exact.TransferMethodPermit2:
+		return e.createPaymentPermit2(ctx, requirements, extra)
+	}
 
-	if err := json.Unmarshal([]byte(*requirements.Extra), &extra); err != nil {
+	token, err := e.token(requirements)
+	if err != nil {
 		return nil, err
 	}
 
-	chain, ok := map[string]*math.HexOrDecimal256{
-		"base":         math.NewHexOrDecimal256(8453),
-		"base-sepolia": math.NewHexOrDecimal256(84532),
-	}[requirements.Network]
-
-	if !ok {
-		return nil, fmt.Errorf("unknown network: %s", requirements.Network)
+	payload, err := e.preparePaymentHeader(ctx, token, requirements)
+	if err != nil {
+		return nil, err
 	}
 
+	authorization := payload.Authorization
+
 	typedData := apitypes.TypedData{
 		Types: apitypes.Types{
 			"TransferWithAuthorization": []apitypes.Type{
@@ -100,85 +162,91 @@ func (e *ExactEvm) createPaymentExactEvm(requirements types.PaymentRequirements)
 		},
 		PrimaryType: "TransferWithAuthorization",
 		Domain: apitypes.TypedDataDomain{
-			Name:              extra["name"].(string),    // TODO
-			Version:           extra["version"].(string), // TODO
-			ChainId:           chain,
-			VerifyingContract: requirements.Asset,
+			Name:              token.Name,
+			Version:           token.Version,
+			ChainId:           (*math.HexOrDecimal256)(token.ChainID),
+			VerifyingContract: token.Address.Hex(),
 			// Salt:              "0x", TODO ?
 		},
 		Message: apitypes.TypedDataMessage{
-			"from":        payload.Payload.Authorization.From,
-			"to":          payload.Payload.Authorization.To,
-			"value":       payload.Payload.Authorization.Value,
-			"validAfter":  payload.Payload.Authorization.ValidAfter,
-			"validBefore": payload.Payload.Authorization.ValidBefore,
-			"nonce":       payload.Payload.Authorization.Nonce,
+			"from":        authorization.From,
+			"to":          authorization.To,
+			"value":       authorization.Value,
+			"validAfter":  authorization.ValidAfter,
+			"validBefore": authorization.ValidBefore,
+			"nonce":       authorization.Nonce,
 		},
 	}
 
-	hash, data, err := apitypes.TypedDataAndHash(typedData)
+	payload.Signature, err = eip712.Sign(ctx, e.signer, typedData, e.log)
 	if err != nil {
 		return nil, err
 	}
 
-	e.log.Debug("ERC-3009 hash", slog.String("hex", hexutil.Encode(hash)))
-	e.log.Debug("ERC-3009 message", slog.String("hex", hexutil.Encode([]byte(data))))
+	e.log.Info(
+		"x402 payment authorized",
+		slog.String("from", authorization.From),
+		slog.String("to", authorization.To),
+		slog.String("value", authorization.Value),
+		slog.String("scheme", requirements.Scheme),
+		slog.String("network", requirements.Network),
+		slog.String("name", token.Name),
+	)
 
-	sig, err := e.signer.Sign(hash)
+	return &api.PaymentPayload{
+		X402Version: 1,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload:     payload,
+	}, nil
+}
+
+// token returns the known token identified by the requirements' network
+// and asset.  The EIP-712 domain name and version are taken from the
+// registry rather than trusting the seller, but if the seller provides
+// them they must agree with the registry.
+func (e *ExactEvm) token(requirements types.PaymentRequirements) (api.Token, error) {
+	extra, err := exact.ParseExtra(requirements)
 	if err != nil {
-		return nil, err
+		return api.Token{}, err
 	}
 
-	sig[64] += 27
-
-	e.log.Debug("Signature", slog.String("hex", hex.EncodeToString(sig)))
+	network, err := e.networks.Lookup(requirements.Network)
+	if err != nil {
+		return api.Token{}, err
+	}
 
-	payload.Payload.Signature = hexutil.Encode(sig)
+	return e.tokens.Resolve(network.ChainID, common.HexToAddress(requirements.Asset), extra.Name, extra.Version)
+}
 
-	sig[64] -= 27
+func (e *ExactEvm) preparePaymentHeader(ctx context.Context, token api.Token, details types.PaymentRequirements) (*types.ExactEvmPayload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	pubKey, err := crypto.SigToPub(hash, sig)
+	nonce, err := e.options.Nonce(ctx, api.NonceScope{
+		ChainID:  token.ChainID,
+		Contract: token.Address,
+		Account:  e.signer.Address(),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	addr := crypto.PubkeyToAddress(*pubKey)
-
-	e.log.Debug("Recovered address", slog.String("hex", addr.Hex()))
-
-	e.log.Info(
-		"x402 payment authorized",
-		slog.String("from", payload.Payload.Authorization.From),
-		slog.String("to", payload.Payload.Authorization.To),
-		slog.String("value", payload.Payload.Authorization.Value),
-		slog.String("scheme", requirements.Scheme),
-		slog.String("network", requirements.Network),
-		slog.String("name", extra["name"].(string)),
-	)
-
-	return payload, nil
-}
-
-func (e *ExactEvm) preparePaymentHeader(details types.PaymentRequirements) (*types.PaymentPayload, error) {
-	nonce := e.nonceFunc()
-
-	validAfter := strconv.FormatInt(e.nowFunc().Add(-10*time.Minute).Unix(), 10)
-	validBefore := strconv.FormatInt(e.nowFunc().Add(time.Duration(details.MaxTimeoutSeconds)*time.Second).Unix(), 10)
+	after, expiry, err := e.options.Window(ctx, time.Duration(details.MaxTimeoutSeconds)*time.Second)
+	if err != nil {
+		return nil, err
+	}
 
-	return &types.PaymentPayload{
-		X402Version: 1,
-		Scheme:      details.Scheme,
-		Network:     details.Network,
-		Payload: &types.ExactEvmPayload{
-			Signature: "",
-			Authorization: &types.ExactEvmPayloadAuthorization{
-				From:        e.signer.Address().Hex(),
-				To:          details.PayTo,
-				Value:       details.MaxAmountRequired,
-				ValidAfter:  validAfter,
-				ValidBefore: validBefore,
-				Nonce:       hexutil.Encode(nonce),
-			},
+	return &types.ExactEvmPayload{
+		Signature: "",
+		Authorization: &types.ExactEvmPayloadAuthorization{
+			From:        e.signer.Address().Hex(),
+			To:          details.PayTo,
+			Value:       details.MaxAmountRequired,
+			ValidAfter:  strconv.FormatInt(after.Unix(), 10),
+			ValidBefore: strconv.FormatInt(expiry.Unix(), 10),
+			Nonce:       hexutil.Encode(nonce),
 		},
 	}, nil
 }
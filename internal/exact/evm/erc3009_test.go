@@ -2,16 +2,25 @@ package evm_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/v3/golden"
 
+	"github.com/selesy/x402-buyer/internal/exact"
 	"github.com/selesy/x402-buyer/internal/exact/evm"
 	"github.com/selesy/x402-buyer/internal/signer"
 	"github.com/selesy/x402-buyer/pkg/api"
@@ -21,21 +30,59 @@ import (
 func TestNewClient(t *testing.T) {
 	t.Parallel()
 
-	paymentRequestJSON := golden.Get(t, "x402_org_payment_request.json")
-
 	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
 	require.NoError(t, err)
 
+	assertGoldenPayment(t, signer)
+}
+
+func TestExternalSigner(t *testing.T) {
+	t.Parallel()
+
+	srv := apitest.NewExternalSigner(t)
+
+	signer, err := signer.NewExternalSigner(context.Background(), srv.URL, common.Address{})
+	require.NoError(t, err)
+
+	t.Cleanup(signer.Close)
+
+	// The external signer produces the same signature as a local key.
+	requirements := assertGoldenPayment(t, signer)
+
+	// The external signer received the full typed data.
+	typedData := srv.TypedData()
+	require.Len(t, typedData, 1)
+	assert.Equal(t, "TransferWithAuthorization", typedData[0].PrimaryType)
+	assert.Equal(t, requirements.MaxAmountRequired, typedData[0].Message["value"])
+}
+
+func TestWalletSigner(t *testing.T) {
+	t.Parallel()
+
+	wallet, acct := apitest.Wallet(t)
+
+	signer, err := signer.NewWalletSigner(wallet, acct, []byte(apitest.Passphrase))
+	require.NoError(t, err)
+
+	assertGoldenPayment(t, signer)
+}
+
+// assertGoldenPayment pays the x402.org payment request using the signer
+// and checks that the payment matches the golden payload.  The payment's
+// requirements are returned.
+func assertGoldenPayment(t *testing.T, signer api.Signer) types.PaymentRequirements {
+	t.Helper()
+
+	paymentRequestJSON := golden.Get(t, "x402_org_payment_request.json")
+
 	var paymentRequest api.PaymentRequest
 
 	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
-	require.NotNil(t, paymentRequest)
-	require.NotNil(t, paymentRequest.Accepts)
 	require.Len(t, paymentRequest.Accepts, 1)
 
 	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
 
-	payer, err := evm.NewExactEvm(signer, fixedNowFunc(t), fixedNonceFunc(t), log)
+	payer, err := evm.NewExactEvm(signer, api.NewNetworks(), exact.NewTokens(), log, api.WithNowFunc(fixedNowFunc(t)), api.WithNonceFunc(fixedNonceFunc(t)))
 	require.NoError(t, err)
 
 	paymentPayload, err := payer.Pay(paymentRequest.Accepts[0])
@@ -48,6 +95,229 @@ func TestNewClient(t *testing.T) {
 	require.NoError(t, json.Indent(buf, data, "", "  "))
 
 	golden.Assert(t, buf.String()+"\n", "x402_org_payment_payload.golden")
+
+	return paymentRequest.Accepts[0]
+}
+
+// policySigner is an api.TypedDataSigner that refuses to sign payments
+// over a limit or sign bare digests.
+type policySigner struct {
+	*signer.ECDSASigner
+
+	limit int64
+	calls int
+}
+
+func (s *policySigner) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("refusing to sign a bare digest")
+}
+
+func (s *policySigner) SignTypedData(_ context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	s.calls++
+
+	value, err := strconv.ParseInt(typedData.Message["value"].(string), 10, 64)
+	if err != nil || value > s.limit {
+		return nil, errors.New("payment exceeds policy")
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := s.ECDSASigner.Sign(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	sig[64] += 27
+
+	return sig, nil
+}
+
+func TestTypedDataSigner(t *testing.T) {
+	t.Parallel()
+
+	paymentRequestJSON := golden.Get(t, "x402_org_payment_request.json")
+
+	var paymentRequest api.PaymentRequest
+
+	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
+
+	ecdsaSigner, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	t.Run("passes - typed data preferred", func(t *testing.T) {
+		t.Parallel()
+
+		signer := &policySigner{ECDSASigner: ecdsaSigner, limit: 10000}
+
+		assertGoldenPayment(t, signer)
+		assert.Equal(t, 1, signer.calls)
+	})
+
+	t.Run("fails - refused by policy", func(t *testing.T) {
+		t.Parallel()
+
+		signer := &policySigner{ECDSASigner: ecdsaSigner, limit: 1}
+
+		payer, err := evm.NewExactEvm(signer, api.NewNetworks(), exact.NewTokens(), log)
+		require.NoError(t, err)
+
+		_, err = payer.Pay(paymentRequest.Accepts[0])
+		require.ErrorContains(t, err, "payment exceeds policy")
+	})
+}
+
+func TestPayContext(t *testing.T) {
+	t.Parallel()
+
+	paymentRequestJSON := golden.Get(t, "x402_org_payment_request.json")
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	var paymentRequest api.PaymentRequest
+
+	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	t.Run("passes - validity limited by deadline", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		deadline := now.Add(30 * time.Second)
+
+		payer, err := evm.NewExactEvm(signer, api.NewNetworks(), exact.NewTokens(), log, api.WithNowFunc(func() time.Time { return now }), api.WithNonceFunc(fixedNonceFunc(t)))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		t.Cleanup(cancel)
+
+		paymentPayload, err := payer.PayContext(ctx, paymentRequest.Accepts[0])
+		require.NoError(t, err)
+		validBefore, err := strconv.ParseInt(paymentPayload.Payload.Authorization.ValidBefore, 10, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, deadline.Unix(), validBefore, 1)
+	})
+
+	t.Run("fails - context cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		payer, err := evm.NewExactEvm(signer, api.NewNetworks(), exact.NewTokens(), log, api.WithNowFunc(fixedNowFunc(t)), api.WithNonceFunc(fixedNonceFunc(t)))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = payer.PayContext(ctx, paymentRequest.Accepts[0])
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTokenValidation(t *testing.T) {
+	t.Parallel()
+
+	paymentRequestJSON := golden.Get(t, "x402_org_payment_request.json")
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	var paymentRequest api.PaymentRequest
+
+	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	payer, err := evm.NewExactEvm(signer, api.NewNetworks(), exact.NewTokens(), log, api.WithNowFunc(fixedNowFunc(t)), api.WithNonceFunc(fixedNonceFunc(t)))
+	require.NoError(t, err)
+
+	expected, err := payer.Pay(paymentRequest.Accepts[0])
+	require.NoError(t, err)
+
+	extra := func(s string) *json.RawMessage {
+		raw := json.RawMessage(s)
+
+		return &raw
+	}
+
+	t.Run("passes - domain filled from registry", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Extra = nil
+
+		paymentPayload, err := payer.Pay(requirements)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Payload.Signature, paymentPayload.Payload.Signature)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		asset  string
+		extra  *json.RawMessage
+		target error
+	}{
+		{name: "unknown asset", asset: "0x5FbDB2315678afecb367f032d93F642f64180aa3", target: exact.ErrUnknownToken},
+		{name: "invalid asset", asset: "USDC", target: api.ErrInvalidRequirements},
+		{name: "name mismatch", extra: extra(`{"name":"USD Coin","version":"2"}`), target: exact.ErrTokenMismatch},
+		{name: "name not a string", extra: extra(`{"name":1,"version":"2"}`), target: api.ErrInvalidRequirements},
+		{name: "extra not an object", extra: extra(`[]`), target: api.ErrInvalidRequirements},
+		{name: "version mismatch", extra: extra(`{"name":"USDC","version":"1"}`), target: exact.ErrTokenMismatch},
+	} {
+		t.Run("fails - "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requirements := paymentRequest.Accepts[0]
+			if tc.asset != "" {
+				requirements.Asset = tc.asset
+			}
+
+			if tc.extra != nil {
+				requirements.Extra = tc.extra
+			}
+
+			_, err := payer.Pay(requirements)
+			require.ErrorIs(t, err, tc.target)
+		})
+	}
+}
+
+func TestSmartWallet(t *testing.T) {
+	t.Parallel()
+
+	paymentRequestJSON := golden.Get(t, "x402_org_payment_request.json")
+
+	owner, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	wallet := common.HexToAddress("0xCA11bde05977b3631167028862bE2a173976CA11")
+
+	smartWallet, err := signer.NewSmartWalletSigner(wallet, owner)
+	require.NoError(t, err)
+
+	var paymentRequest api.PaymentRequest
+
+	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	payer, err := evm.NewExactEvm(smartWallet, api.NewNetworks(), exact.NewTokens(), log, api.WithNowFunc(fixedNowFunc(t)), api.WithNonceFunc(fixedNonceFunc(t)))
+	require.NoError(t, err)
+
+	paymentPayload, err := payer.Pay(paymentRequest.Accepts[0])
+	require.NoError(t, err)
+	assert.Equal(t, wallet.Hex(), paymentPayload.Payload.Authorization.From)
+
+	// The owner's signature is used without further adjustment and
+	// therefore recovers to the owner rather than the wallet.
+	sig, err := hexutil.Decode(paymentPayload.Payload.Signature)
+	require.NoError(t, err)
+	require.Len(t, sig, 65)
+	assert.Contains(t, []byte{27, 28}, sig[64])
 }
 
 func fixedNonceFunc(t *testing.T) api.NonceFunc {
@@ -0,0 +1,122 @@
+package evm
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/selesy/x402-buyer/internal/eip712"
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// createPaymentPermit creates an "exact" payment for tokens that support
+// EIP-2612 permits but not ERC-3009.  The spender uses the permit to
+// approve the transfer of the value and then transfers it to the seller.
+func (e *ExactEvm) createPaymentPermit(ctx context.Context, requirements types.PaymentRequirements, extra exact.Extra) (*api.PaymentPayload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := e.token(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	_, deadline, err := e.options.Window(ctx, time.Duration(requirements.MaxTimeoutSeconds)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := e.options.PermitNonce(ctx, token, e.signer.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &api.PermitEvmPayload{
+		Authorization: &api.PermitAuthorization{
+			Owner:    e.signer.Address().Hex(),
+			Spender:  extra.SpenderOr(requirements.PayTo).Hex(),
+			Value:    requirements.MaxAmountRequired,
+			Nonce:    nonce.String(),
+			Deadline: strconv.FormatInt(deadline.Unix(), 10),
+		},
+	}
+
+	authorization := payload.Authorization
+
+	payload.Signature, err = eip712.Sign(ctx, e.signer, eip712.Permit(token, authorization), e.log)
+	if err != nil {
+		return nil, err
+	}
+
+	e.log.Info(
+		"x402 payment authorized",
+		slog.String("from", authorization.Owner),
+		slog.String("spender", authorization.Spender),
+		slog.String("value", authorization.Value),
+		slog.String("scheme", requirements.Scheme),
+		slog.String("network", requirements.Network),
+		slog.String("method", exact.TransferMethodPermit),
+		slog.String("name", token.Name),
+	)
+
+	return &api.PaymentPayload{
+		X402Version: 1,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload:     payload,
+	}, nil
+}
+
+// createPaymentPermit2 creates an "exact" payment for any ERC-20 token
+// that the buyer has approved the Permit2 contract to transfer.
+func (e *ExactEvm) createPaymentPermit2(ctx context.Context, requirements types.PaymentRequirements, extra exact.Extra) (*api.PaymentPayload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	network, err := e.networks.Lookup(requirements.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	// The seller's name and version aren't relevant since the Permit2
+	// contract's EIP-712 domain is used.
+	token, err := e.tokens.Lookup(network.ChainID, common.HexToAddress(requirements.Asset))
+	if err != nil {
+		return nil, err
+	}
+
+	authorization, signature, err := exact.SignPermit2(ctx, e.signer, e.options, network.ChainID, token, extra.SpenderOr(requirements.PayTo), requirements, e.log)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &api.Permit2EvmPayload{
+		Authorization: authorization,
+		Signature:     signature,
+	}
+
+	e.log.Info(
+		"x402 payment authorized",
+		slog.String("from", authorization.From),
+		slog.String("spender", authorization.Spender),
+		slog.String("value", authorization.Permitted.Amount),
+		slog.String("scheme", requirements.Scheme),
+		slog.String("network", requirements.Network),
+		slog.String("method", exact.TransferMethodPermit2),
+		slog.String("name", token.Name),
+	)
+
+	return &api.PaymentPayload{
+		X402Version: 1,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload:     payload,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/golden"
+
+	"github.com/selesy/x402-buyer/internal/eip712"
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/internal/exact/evm"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestPermit(t *testing.T) {
+	t.Parallel()
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	permitNonce := func(_ context.Context, token api.Token, owner common.Address) (*big.Int, error) {
+		assert.Equal(t, common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"), token.Address)
+		assert.Equal(t, signer.Address(), owner)
+
+		return big.NewInt(7), nil
+	}
+
+	payer, err := evm.NewExactEvm(
+		signer,
+		api.NewNetworks(),
+		exact.NewTokens(),
+		log,
+		api.WithNowFunc(fixedNowFunc(t)),
+		api.WithNonceFunc(fixedNonceFunc(t)),
+		api.WithPermitNonceFunc(permitNonce),
+	)
+	require.NoError(t, err)
+
+	requirements := func(t *testing.T, name string) api.PaymentRequest {
+		t.Helper()
+
+		var paymentRequest api.PaymentRequest
+
+		require.NoError(t, json.Unmarshal(golden.Get(t, name), &paymentRequest))
+		require.Len(t, paymentRequest.Accepts, 1)
+
+		return paymentRequest
+	}
+
+	t.Run("passes - EIP-2612 permit", func(t *testing.T) {
+		t.Parallel()
+
+		paymentRequest := requirements(t, "permit_payment_request.json")
+
+		paymentPayload, err := payer.PayPayload(t.Context(), paymentRequest.Accepts[0])
+		require.NoError(t, err)
+
+		assertGolden(t, paymentPayload, "permit_payment_payload.golden")
+
+		payload, ok := paymentPayload.Permit()
+		require.True(t, ok)
+		assert.Equal(t, "7", payload.Authorization.Nonce)
+
+		token, err := exact.NewTokens().Lookup(big.NewInt(84532), common.HexToAddress(paymentRequest.Accepts[0].Asset))
+		require.NoError(t, err)
+
+		assertRecovers(t, eip712.Permit(token, payload.Authorization), payload.Signature, signer.Address())
+	})
+
+	t.Run("passes - Permit2", func(t *testing.T) {
+		t.Parallel()
+
+		paymentRequest := requirements(t, "permit2_payment_request.json")
+
+		paymentPayload, err := payer.PayPayload(t.Context(), paymentRequest.Accepts[0])
+		require.NoError(t, err)
+
+		assertGolden(t, paymentPayload, "permit2_payment_payload.golden")
+
+		payload, ok := paymentPayload.Permit2()
+		require.True(t, ok)
+		assert.Equal(t, "10000", payload.Authorization.Permitted.Amount)
+
+		assertRecovers(t, eip712.PermitTransferFrom(big.NewInt(84532), payload.Authorization), payload.Signature, signer.Address())
+	})
+
+	t.Run("fails - permit nonce unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		paymentRequest := requirements(t, "permit_payment_request.json")
+
+		payer, err := evm.NewExactEvm(signer, api.NewNetworks(), exact.NewTokens(), log)
+		require.NoError(t, err)
+
+		_, err = payer.Pay(paymentRequest.Accepts[0])
+		require.ErrorIs(t, err, api.ErrPermitNonceUnavailable)
+	})
+
+	t.Run("fails - unsupported transfer method", func(t *testing.T) {
+		t.Parallel()
+
+		paymentRequest := requirements(t, "permit_payment_request.json")
+
+		extra := json.RawMessage(`{"assetTransferMethod":"approve"}`)
+		paymentRequest.Accepts[0].Extra = &extra
+
+		_, err := payer.Pay(paymentRequest.Accepts[0])
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+}
+
+func assertGolden(t *testing.T, paymentPayload *api.PaymentPayload, name string) {
+	t.Helper()
+
+	data, err := json.Marshal(paymentPayload)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, json.Indent(buf, data, "", "  "))
+
+	golden.Assert(t, buf.String()+"\n", name)
+}
+
+func assertRecovers(t *testing.T, typedData apitypes.TypedData, signature string, address common.Address) {
+	t.Helper()
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+
+	sig, err := hexutil.Decode(signature)
+	require.NoError(t, err)
+
+	sig[64] -= 27
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	require.NoError(t, err)
+	assert.Equal(t, address, crypto.PubkeyToAddress(*pubKey))
+}
@@ -1,47 +1,170 @@
 package exact
 
-// From https://github.com/coinbase/x402/blob/094dcd2b95b5e13e8673264cc026d080417ee142/python/x402/src/x402/chains.py#L4
-// KNOWN_TOKENS = {
-//     "84532": [
-//         {
-//             "human_name": "usdc",
-//             "address": "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
-//             "name": "USDC",
-//             "decimals": 6,
-//             "version": "2",
-//         }
-//     ],
-//     "8453": [
-//         {
-//             "human_name": "usdc",
-//             "address": "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
-//             "name": "USD Coin",  # needs to be exactly what is returned by name() on contract
-//             "decimals": 6,
-//             "version": "2",
-//         }
-//     ],
-//     "43113": [
-//         {
-//             "human_name": "usdc",
-//             "address": "0x5425890298aed601595a70AB815c96711a31Bc65",
-//             "name": "USD Coin",
-//             "decimals": 6,
-//             "version": "2",
-//         }
-//     ],
-//     "43114": [
-//         {
-//             "human_name": "usdc",
-//             "address": "0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E",
-//             "name": "USDC",
-//             "decimals": 6,
-//             "version": "2",
-//         }
-//     ],
-// }
-
-// From https://github.com/coinbase/x402/blob/094dcd2b95b5e13e8673264cc026d080417ee142/go/pkg/gin/middleware.go#L120
-// if options.Testnet {
-// 	network = "base-sepolia"
-// 	usdcAddress = "0x036CbD53842c5426634e7929541eC2318f3dCF7e"
-// }
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// ErrUnknownToken is returned when the asset named in a seller's payment
+// requirements is not a known token.
+var ErrUnknownToken = errors.New("unknown token")
+
+// ErrTokenMismatch is returned when the EIP-712 domain provided in a
+// seller's payment requirements doesn't match the known token.
+var ErrTokenMismatch = errors.New("payment requirements don't match known token")
+
+// KnownTokens are the ERC-3009 capable tokens that x402 sellers accept
+// payments in.
+//
+// Originally from https://github.com/coinbase/x402/blob/094dcd2b95b5e13e8673264cc026d080417ee142/python/x402/src/x402/chains.py#L4
+var KnownTokens = []api.Token{
+	{
+		ChainID:  big.NewInt(8453),
+		Address:  common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"),
+		Name:     "USD Coin",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(84532),
+		Address:  common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"),
+		Name:     "USDC",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(43114),
+		Address:  common.HexToAddress("0xB97EF9Ef8734C71904D8002F8b6Bc66Dd9c48a6E"),
+		Name:     "USD Coin",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(43113),
+		Address:  common.HexToAddress("0x5425890298aed601595a70AB815c96711a31Bc65"),
+		Name:     "USD Coin",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(137),
+		Address:  common.HexToAddress("0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359"),
+		Name:     "USD Coin",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(80002),
+		Address:  common.HexToAddress("0x41E94Eb019C0762f9Bfcf9Fb1E58725BfB0e7582"),
+		Name:     "USDC",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(1329),
+		Address:  common.HexToAddress("0xe15fC38F6D8c56aF07bbCBe3BAf5708A2Bf42392"),
+		Name:     "USDC",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(1328),
+		Address:  common.HexToAddress("0x4fCF1784B31630811181f670Aea7A7bEF803eaED"),
+		Name:     "USDC",
+		Version:  "2",
+		Decimals: 6,
+	},
+	{
+		ChainID:  big.NewInt(4689),
+		Address:  common.HexToAddress("0xcdf79194c6c285077a58da47641d4dbe51f63542"),
+		Name:     "Bridged USDC",
+		Version:  "2",
+		Decimals: 6,
+	},
+}
+
+type tokenKey struct {
+	chainID string
+	address common.Address
+}
+
+// Tokens is a registry of the tokens that payments can be made with.  A
+// Tokens registry is safe for concurrent use.
+type Tokens struct {
+	mu     sync.RWMutex
+	tokens map[tokenKey]api.Token
+}
+
+// NewTokens returns a Tokens registry containing the KnownTokens.
+func NewTokens() *Tokens {
+	t := &Tokens{
+		tokens: map[tokenKey]api.Token{},
+	}
+
+	for _, token := range KnownTokens {
+		_ = t.Register(token)
+	}
+
+	return t
+}
+
+// Register adds a token to the registry, replacing any token with the
+// same chain ID and address.
+func (t *Tokens) Register(token api.Token) error {
+	if token.ChainID == nil || token.ChainID.Sign() <= 0 {
+		return fmt.Errorf("token %s requires a positive chain ID", token.Address.Hex())
+	}
+
+	if token.Name == "" || token.Version == "" {
+		return fmt.Errorf("token %s requires an EIP-712 name and version", token.Address.Hex())
+	}
+
+	token.ChainID = new(big.Int).Set(token.ChainID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tokens[tokenKey{chainID: token.ChainID.String(), address: token.Address}] = token
+
+	return nil
+}
+
+// Lookup returns the token at the provided address on the chain identified
+// by chainID.
+func (t *Tokens) Lookup(chainID *big.Int, address common.Address) (api.Token, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	token, ok := t.tokens[tokenKey{chainID: chainID.String(), address: address}]
+	if !ok {
+		return api.Token{}, fmt.Errorf("%w: %s on chain %s", ErrUnknownToken, address.Hex(), chainID)
+	}
+
+	return token, nil
+}
+
+// Resolve returns the token at the provided address after verifying that
+// the EIP-712 name and version provided by the seller (if any) match
+// those of the known token.
+func (t *Tokens) Resolve(chainID *big.Int, address common.Address, name, version string) (api.Token, error) {
+	token, err := t.Lookup(chainID, address)
+	if err != nil {
+		return api.Token{}, err
+	}
+
+	if name != "" && name != token.Name {
+		return api.Token{}, fmt.Errorf("%w: name %q, expected %q", ErrTokenMismatch, name, token.Name)
+	}
+
+	if version != "" && version != token.Version {
+		return api.Token{}, fmt.Errorf("%w: version %q, expected %q", ErrTokenMismatch, version, token.Version)
+	}
+
+	return token, nil
+}
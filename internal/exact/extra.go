@@ -0,0 +1,59 @@
+package exact
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Asset transfer methods that can be requested using the
+// assetTransferMethod field of the payment requirements' extra field.
+const (
+	TransferMethodEIP3009 = "eip3009"
+	TransferMethodPermit  = "permit"
+	TransferMethodPermit2 = "permit2"
+)
+
+// Extra holds the fields of the payment requirements' extra field that
+// are used by the EVM payers.
+type Extra struct {
+	// Name and Version are the EIP-712 domain of the token.
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// AssetTransferMethod selects how the token is transferred.  ERC-3009
+	// is used if not provided.
+	AssetTransferMethod string `json:"assetTransferMethod"`
+	// Spender is the address that settles permit-based payments.
+	Spender string `json:"spender"`
+}
+
+// ParseExtra returns the Extra fields of the requirements.
+func ParseExtra(requirements types.PaymentRequirements) (Extra, error) {
+	var extra Extra
+
+	if requirements.Extra != nil {
+		if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+			return Extra{}, fmt.Errorf("failed to unmarshal extra: %w", err)
+		}
+	}
+
+	if extra.AssetTransferMethod == "" {
+		extra.AssetTransferMethod = TransferMethodEIP3009
+	}
+
+	return extra, nil
+}
+
+// SpenderOr returns the address that the seller uses to settle a
+// permit-based payment.  Sellers whose payments are settled by a
+// facilitator name the spender while others settle payments themselves
+// using their payTo address.
+func (e Extra) SpenderOr(payTo string) common.Address {
+	if e.Spender != "" {
+		return common.HexToAddress(e.Spender)
+	}
+
+	return common.HexToAddress(payTo)
+}
@@ -0,0 +1,57 @@
+package exact
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/selesy/x402-buyer/internal/eip712"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// SignPermit2 creates a Permit2 PermitTransferFrom authorization that
+// allows the spender to transfer up to the requirements' MaxAmountRequired
+// of the token from the signer's account and returns it along with its
+// signature.  The Permit2 contract's EIP-712 domain is used rather than
+// the token's.
+func SignPermit2(ctx context.Context, signer api.EVMSigner, options *api.Options, chainID *big.Int, token api.Token, spender common.Address, requirements types.PaymentRequirements, log *slog.Logger) (*api.Permit2Authorization, string, error) {
+	_, deadline, err := options.Window(ctx, time.Duration(requirements.MaxTimeoutSeconds)*time.Second)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Permit2 nonces are unordered (each is a bit in the owner's nonce
+	// bitmap) so the nonce source's next nonce is used rather than one
+	// read from the chain.
+	nonceBytes, err := options.Nonce(ctx, api.NonceScope{
+		ChainID:  chainID,
+		Contract: eip712.Permit2Address,
+		Account:  signer.Address(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	authorization := &api.Permit2Authorization{
+		From: signer.Address().Hex(),
+		Permitted: api.Permit2Permission{
+			Token:  token.Address.Hex(),
+			Amount: requirements.MaxAmountRequired,
+		},
+		Spender:  spender.Hex(),
+		Nonce:    new(big.Int).SetBytes(nonceBytes).String(),
+		Deadline: strconv.FormatInt(deadline.Unix(), 10),
+	}
+
+	signature, err := eip712.Sign(ctx, signer, eip712.PermitTransferFrom(chainID, authorization), log)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return authorization, signature, nil
+}
@@ -0,0 +1,92 @@
+package svm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/selesy/x402-buyer/internal/exact"
+	solana "github.com/selesy/x402-buyer/internal/svm"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// Mint is an SPL token mint that payments can be made with.
+type Mint struct {
+	// Network is the x402 version 1 name of the network the mint is on.
+	Network string
+	// Address is the mint's account address.
+	Address solana.PublicKey
+}
+
+// KnownMints are the SPL token mints that x402 sellers accept payments in.
+var KnownMints = []Mint{
+	{
+		Network: "solana",
+		Address: solana.MustPublicKey("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"),
+	},
+	{
+		Network: "solana-devnet",
+		Address: solana.MustPublicKey("4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"),
+	},
+}
+
+type mintKey struct {
+	network string
+	address solana.PublicKey
+}
+
+// Mints is a registry of the mints that payments can be made with.  A
+// Mints registry is safe for concurrent use.
+type Mints struct {
+	mu    sync.RWMutex
+	mints map[mintKey]Mint
+}
+
+// NewMints returns a Mints registry containing the KnownMints.
+func NewMints() *Mints {
+	m := &Mints{
+		mints: map[mintKey]Mint{},
+	}
+
+	for _, mint := range KnownMints {
+		_ = m.Register(mint)
+	}
+
+	return m
+}
+
+// Register adds a mint to the registry, replacing any mint with the same
+// network and address.  The mint's network can be identified by its name
+// or CAIP-2 identifier.
+func (m *Mints) Register(mint Mint) error {
+	network, ok := LookupNetwork(mint.Network)
+	if !ok {
+		return fmt.Errorf("%w: %s", api.ErrUnknownNetwork, mint.Network)
+	}
+
+	mint.Network = network.Name
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mints[mintKey{network: mint.Network, address: mint.Address}] = mint
+
+	return nil
+}
+
+// Lookup returns the mint at the provided address on the network
+// (identified by its name or CAIP-2 identifier.)
+func (m *Mints) Lookup(network string, address solana.PublicKey) (Mint, error) {
+	if known, ok := LookupNetwork(network); ok {
+		network = known.Name
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mint, ok := m.mints[mintKey{network: network, address: address}]
+	if !ok {
+		return Mint{}, fmt.Errorf("%w: %s on %s", exact.ErrUnknownToken, address, network)
+	}
+
+	return mint, nil
+}
@@ -0,0 +1,310 @@
+// Package svm implements the "exact" scheme on Solana.
+package svm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/coinbase/x402/go/pkg/types"
+
+	solana "github.com/selesy/x402-buyer/internal/svm"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var (
+	_ api.PayloadPayer = (*ExactSvm)(nil)
+	_ api.NetworkPayer = (*ExactSvm)(nil)
+	_ api.Validator    = (*ExactSvm)(nil)
+)
+
+const (
+	// computeUnitLimit comfortably covers a TransferChecked instruction
+	// for both the Token and Token-2022 programs.
+	computeUnitLimit = 20_000
+	// computeUnitPrice is the priority fee in micro-lamports per compute
+	// unit.  The fee payer pays it, so it's kept to the minimum.
+	computeUnitPrice = 1
+)
+
+// Network is a Solana cluster on which x402 payments can be made.
+type Network struct {
+	// Name is the network's x402 version 1 name.
+	Name string
+	// CAIP2 is the network's CAIP-2 identifier (used by x402 version 2.)
+	CAIP2 string
+	// RPC is the default JSON-RPC endpoint for the cluster.
+	RPC string
+}
+
+// KnownNetworks are the Solana clusters that x402 sellers accept payments
+// on.
+var KnownNetworks = []Network{
+	{
+		Name:  "solana",
+		CAIP2: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp",
+		RPC:   "https://api.mainnet-beta.solana.com",
+	},
+	{
+		Name:  "solana-devnet",
+		CAIP2: "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1",
+		RPC:   "https://api.devnet.solana.com",
+	},
+}
+
+// LookupNetwork returns the known network identified by either its name
+// or CAIP-2 identifier.
+func LookupNetwork(network string) (Network, bool) {
+	for _, known := range KnownNetworks {
+		if network == known.Name || network == known.CAIP2 {
+			return known, true
+		}
+	}
+
+	return Network{}, false
+}
+
+// ExactSvm is an api.Payer that handles payment requests on Solana for
+// the "exact" scheme.
+//
+// The payment is an SPL token TransferChecked transaction from the
+// buyer's associated token account to the seller's.  The seller's
+// facilitator pays the transaction fee so the transaction is only
+// partially signed by the buyer; the facilitator adds the fee payer's
+// signature when the payment is settled.  The seller's associated token
+// account must already exist.
+//
+// Payments are only made with registered mints and the buyer's account
+// can't be used as the fee payer.
+type ExactSvm struct {
+	signer  api.SVMSigner
+	rpcs    map[string]*solana.RPC
+	mints   *Mints
+	options *api.Options
+	log     *slog.Logger
+}
+
+// NewExactSvm creates an ExactSvm that uses the JSON-RPC endpoints
+// (keyed by network name or CAIP-2 identifier) to retrieve block hashes
+// and token mints.  Networks without an endpoint use the cluster's public
+// endpoint.  If client is nil, http.DefaultClient is used.
+func NewExactSvm(signer api.Signer, endpoints map[string]string, mints *Mints, client *http.Client, log *slog.Logger, opts ...api.Option) (*ExactSvm, error) {
+	s, ok := signer.(api.SVMSigner)
+	if !ok {
+		return nil, fmt.Errorf("%w: the Exact SVM scheme requires an SVM signer", api.ErrUnsupportedSigner)
+	}
+
+	options, err := api.NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcs := make(map[string]*solana.RPC, len(KnownNetworks))
+
+	for _, network := range KnownNetworks {
+		rpcs[network.Name] = solana.NewRPC(network.RPC, client)
+	}
+
+	for name, endpoint := range endpoints {
+		network, ok := LookupNetwork(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", api.ErrUnknownNetwork, name)
+		}
+
+		rpcs[network.Name] = solana.NewRPC(endpoint, client)
+	}
+
+	return &ExactSvm{
+		signer:  s,
+		rpcs:    rpcs,
+		mints:   mints,
+		options: options,
+		log:     log,
+	}, nil
+}
+
+// Pay implements api.Payer.
+func (e *ExactSvm) Pay(requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	return e.PayContext(context.Background(), requirements)
+}
+
+// PayContext implements api.ContextPayer.
+func (e *ExactSvm) PayContext(ctx context.Context, requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	payment, err := e.PayPayload(ctx, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment.Types()
+}
+
+// PayPayload implements api.PayloadPayer.
+func (e *ExactSvm) PayPayload(ctx context.Context, requirements types.PaymentRequirements) (*api.PaymentPayload, error) {
+	if err := e.Validate(requirements); err != nil {
+		return nil, err
+	}
+
+	switch requirements.Scheme {
+	case "exact":
+		return e.createPaymentExactSvm(ctx, requirements)
+	default:
+		return nil, fmt.Errorf("unknown payment scheme : %w, %s", http.ErrNotSupported, requirements.Scheme)
+	}
+}
+
+// Supports implements api.NetworkPayer.
+func (e *ExactSvm) Supports(network string) bool {
+	_, ok := LookupNetwork(network)
+
+	return ok
+}
+
+// Validate implements api.Validator.
+func (e *ExactSvm) Validate(requirements types.PaymentRequirements) error {
+	var verr api.ValidationError
+
+	api.ValidateRequirements(&verr, requirements)
+
+	if requirements.Scheme != "" && requirements.Scheme != string(api.SchemeExact) {
+		verr.Add("scheme", "%q is not supported", requirements.Scheme)
+	}
+
+	if requirements.Network != "" && !e.Supports(requirements.Network) {
+		verr.Add("network", "%q is not a known Solana network", requirements.Network)
+	}
+
+	if requirements.PayTo != "" {
+		if _, err := solana.ParsePublicKey(requirements.PayTo); err != nil {
+			verr.Add("payTo", "%q is not a Solana address", requirements.PayTo)
+		}
+	}
+
+	if requirements.Asset != "" {
+		if _, err := solana.ParsePublicKey(requirements.Asset); err != nil {
+			verr.Add("asset", "%q is not a Solana address", requirements.Asset)
+		}
+	}
+
+	if _, err := strconv.ParseUint(requirements.MaxAmountRequired, 10, 64); errors.Is(err, strconv.ErrRange) {
+		verr.Add("maxAmountRequired", "%s exceeds the largest SPL token amount", requirements.MaxAmountRequired)
+	}
+
+	if payer, err := feePayer(requirements); err != nil {
+		verr.Add("extra.feePayer", "%s", err.Error())
+	} else if payer == solana.PublicKey(e.signer.PublicKey()) {
+		verr.Add("extra.feePayer", "%q is the buyer's account", payer)
+	}
+
+	return verr.Err()
+}
+
+// Scheme implements api.Payer.
+func (e *ExactSvm) Scheme() api.Scheme {
+	return api.SchemeExact
+}
+
+func (e *ExactSvm) createPaymentExactSvm(ctx context.Context, requirements types.PaymentRequirements) (*api.PaymentPayload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Validate has already checked each of these values.
+	network, _ := LookupNetwork(requirements.Network)
+	payTo, _ := solana.ParsePublicKey(requirements.PayTo)
+	mint, _ := solana.ParsePublicKey(requirements.Asset)
+	payer, _ := feePayer(requirements)
+	amount, _ := strconv.ParseUint(requirements.MaxAmountRequired, 10, 64)
+
+	if _, err := e.mints.Lookup(network.Name, mint); err != nil {
+		return nil, err
+	}
+
+	owner := solana.PublicKey(e.signer.PublicKey())
+	rpc := e.rpcs[network.Name]
+
+	tokenProgram, decimals, err := rpc.Mint(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := solana.AssociatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := solana.AssociatedTokenAddress(payTo, mint, tokenProgram)
+	if err != nil {
+		return nil, err
+	}
+
+	blockhash, err := rpc.LatestBlockhash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := solana.CompileMessage(payer, []solana.Instruction{
+		solana.SetComputeUnitLimit(computeUnitLimit),
+		solana.SetComputeUnitPrice(computeUnitPrice),
+		solana.TransferChecked(tokenProgram, source, mint, destination, owner, amount, decimals),
+	}, blockhash)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := api.SignContext(ctx, e.signer, msg.Serialize())
+	if err != nil {
+		return nil, err
+	}
+
+	tx := solana.NewTransaction(msg)
+	if err := tx.AddSignature(owner, sig); err != nil {
+		return nil, err
+	}
+
+	e.log.Info(
+		"x402 payment authorized",
+		slog.String("from", owner.String()),
+		slog.String("to", requirements.PayTo),
+		slog.String("value", requirements.MaxAmountRequired),
+		slog.String("scheme", requirements.Scheme),
+		slog.String("network", requirements.Network),
+		slog.String("mint", mint.String()),
+	)
+
+	return &api.PaymentPayload{
+		X402Version: 1,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload: &api.ExactSvmPayload{
+			Transaction: base64.StdEncoding.EncodeToString(tx.Serialize()),
+		},
+	}, nil
+}
+
+// feePayer returns the account the seller has designated to pay the
+// transaction's fee.
+func feePayer(requirements types.PaymentRequirements) (solana.PublicKey, error) {
+	if requirements.Extra == nil {
+		return solana.PublicKey{}, errors.New("is required")
+	}
+
+	var extra struct {
+		FeePayer *string `json:"feePayer"`
+	}
+
+	if err := json.Unmarshal(*requirements.Extra, &extra); err != nil || extra.FeePayer == nil {
+		return solana.PublicKey{}, errors.New("is required")
+	}
+
+	key, err := solana.ParsePublicKey(*extra.FeePayer)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("%q is not a Solana address", *extra.FeePayer)
+	}
+
+	return key, nil
+}
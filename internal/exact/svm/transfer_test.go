@@ -0,0 +1,235 @@
+package svm_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/golden"
+
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/internal/exact/svm"
+	"github.com/selesy/x402-buyer/internal/signer"
+	solana "github.com/selesy/x402-buyer/internal/svm"
+	"github.com/selesy/x402-buyer/pkg/api"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+const blockhash = "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N"
+
+func TestExactSvm(t *testing.T) {
+	t.Parallel()
+
+	paymentRequestJSON := golden.Get(t, "svm_payment_request.json")
+
+	var paymentRequest api.PaymentRequest
+
+	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
+	require.Len(t, paymentRequest.Accepts, 1)
+
+	seed, err := hex.DecodeString(apitest.Ed25519SeedHex)
+	require.NoError(t, err)
+
+	signer, err := signer.NewEd25519SignerFromBytes(seed)
+	require.NoError(t, err)
+
+	rpc := newMockRPC(t, solana.TokenProgramID)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	payer, err := svm.NewExactSvm(signer, map[string]string{"solana-devnet": rpc.URL}, svm.NewMints(), rpc.Client(), log)
+	require.NoError(t, err)
+
+	t.Run("passes", func(t *testing.T) {
+		t.Parallel()
+
+		paymentPayload, err := payer.PayPayload(t.Context(), paymentRequest.Accepts[0])
+		require.NoError(t, err)
+
+		data, err := json.Marshal(paymentPayload)
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, json.Indent(buf, data, "", "  "))
+
+		golden.Assert(t, buf.String()+"\n", "svm_payment_payload.golden")
+
+		payload, ok := paymentPayload.ExactSvm()
+		require.True(t, ok)
+
+		tx, err := base64.StdEncoding.DecodeString(payload.Transaction)
+		require.NoError(t, err)
+
+		// Two signatures are required: the fee payer's (left empty for
+		// the facilitator) and the buyer's.
+		const sigSize = ed25519.SignatureSize
+
+		require.Equal(t, byte(2), tx[0])
+		assert.Equal(t, make([]byte, sigSize), tx[1:1+sigSize])
+		assert.True(t, ed25519.Verify(signer.PublicKey(), tx[1+2*sigSize:], tx[1+sigSize:1+2*sigSize]))
+
+		msg := tx[1+2*sigSize:]
+		feePayer := solana.MustPublicKey("CKPKJWNdJEqa81x7CkZ14BVPiY6y16Sxs7owznqtWYp5")
+		assert.Equal(t, feePayer[:], msg[5:5+solana.PublicKeySize])
+	})
+
+	t.Run("passes - CAIP-2 network", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Network = "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1"
+
+		_, err := payer.PayPayload(t.Context(), requirements)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails - missing fee payer", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Extra = nil
+
+		_, err := payer.Pay(requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - EVM address", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.PayTo = "0x60ac86571E55F9735F00cE9e28361d203977B260"
+
+		_, err := payer.Pay(requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - amount exceeds 64 bits", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.MaxAmountRequired = "18446744073709551616"
+
+		_, err := payer.Pay(requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - unknown mint", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Asset = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+
+		_, err := payer.PayPayload(t.Context(), requirements)
+		require.ErrorIs(t, err, exact.ErrUnknownToken)
+	})
+
+	t.Run("fails - buyer is fee payer", func(t *testing.T) {
+		t.Parallel()
+
+		extra := json.RawMessage(`{"feePayer":"` + solana.PublicKey(signer.PublicKey()).String() + `"}`)
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Extra = &extra
+
+		_, err := payer.PayPayload(t.Context(), requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - asset is not a mint", func(t *testing.T) {
+		t.Parallel()
+
+		rpc := newMockRPC(t, solana.SystemProgramID)
+
+		payer, err := svm.NewExactSvm(signer, map[string]string{"solana-devnet": rpc.URL}, svm.NewMints(), rpc.Client(), log)
+		require.NoError(t, err)
+
+		_, err = payer.Pay(paymentRequest.Accepts[0])
+		require.ErrorIs(t, err, solana.ErrNotMint)
+	})
+}
+
+func TestNewExactSvm(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fails - EVM signer", func(t *testing.T) {
+		t.Parallel()
+
+		signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+		require.NoError(t, err)
+
+		_, err = svm.NewExactSvm(signer, nil, svm.NewMints(), nil, slog.Default())
+		require.ErrorIs(t, err, api.ErrUnsupportedSigner)
+	})
+
+	t.Run("fails - unknown network", func(t *testing.T) {
+		t.Parallel()
+
+		seed, err := hex.DecodeString(apitest.Ed25519SeedHex)
+		require.NoError(t, err)
+
+		signer, err := signer.NewEd25519SignerFromBytes(seed)
+		require.NoError(t, err)
+
+		_, err = svm.NewExactSvm(signer, map[string]string{"solana-testnet": "http://localhost"}, svm.NewMints(), nil, slog.Default())
+		require.ErrorIs(t, err, api.ErrUnknownNetwork)
+	})
+}
+
+// newMockRPC returns a JSON-RPC server that responds with a fixed block
+// hash and a USDC-like mint (6 decimals) owned by the mintOwner program.
+func newMockRPC(t *testing.T, mintOwner solana.PublicKey) *httptest.Server {
+	t.Helper()
+
+	mint := make([]byte, 82)
+	mint[44] = 6
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		var result any
+
+		switch req.Method {
+		case "getLatestBlockhash":
+			result = map[string]any{
+				"context": map[string]any{"slot": 1},
+				"value":   map[string]any{"blockhash": blockhash, "lastValidBlockHeight": 150},
+			}
+		case "getAccountInfo":
+			result = map[string]any{
+				"context": map[string]any{"slot": 1},
+				"value": map[string]any{
+					"owner": mintOwner.String(),
+					"data":  []string{base64.StdEncoding.EncodeToString(mint), "base64"},
+				},
+			}
+		default:
+			http.Error(w, "unexpected method: "+req.Method, http.StatusBadRequest)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
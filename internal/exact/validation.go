@@ -0,0 +1,58 @@
+package exact
+
+import (
+	"encoding/json"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// ValidateRequirements performs the validation that applies to payment
+// requirements on EVM networks regardless of their scheme and records any
+// invalid fields in verr.  The requirements' extra field is returned so
+// that the payers can check the fields specific to their scheme.  It's nil
+// if the requirements don't have an extra field or if it isn't a JSON
+// object.
+func ValidateRequirements(verr *api.ValidationError, networks *api.Networks, requirements types.PaymentRequirements) map[string]any {
+	api.ValidateRequirements(verr, requirements)
+
+	if requirements.Network != "" {
+		if _, err := networks.Lookup(requirements.Network); err != nil {
+			verr.Add("network", "%q is not a known EVM network", requirements.Network)
+		}
+	}
+
+	if requirements.PayTo != "" && !common.IsHexAddress(requirements.PayTo) {
+		verr.Add("payTo", "%q is not an EVM address", requirements.PayTo)
+	}
+
+	if requirements.Asset != "" && !common.IsHexAddress(requirements.Asset) {
+		verr.Add("asset", "%q is not an EVM address", requirements.Asset)
+	}
+
+	if requirements.Extra == nil {
+		return nil
+	}
+
+	var extra map[string]any
+
+	if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+		return nil
+	}
+
+	for _, field := range []string{"name", "version", "assetTransferMethod", "spender"} {
+		if value, ok := extra[field]; ok {
+			if _, ok := value.(string); !ok {
+				verr.Add("extra."+field, "is not a string")
+			}
+		}
+	}
+
+	if spender, ok := extra["spender"].(string); ok && !common.IsHexAddress(spender) {
+		verr.Add("extra.spender", "%q is not an EVM address", spender)
+	}
+
+	return extra
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package nonce
+
+import "os"
+
+// lockFile opens (or creates) the file at path.  File locks aren't
+// available on this platform so the file isn't locked and FileStores
+// can't safely be shared between processes.
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package nonce
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile opens (or creates) the file at path and takes an exclusive
+// advisory lock on it, blocking until the lock is available.  The lock is
+// released when the returned file is closed.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package nonce
+
+import (
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile opens (or creates) the file at path and takes an exclusive
+// lock on it, blocking until the lock is available.  The lock is released
+// when the returned file is closed.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, math.MaxUint32, math.MaxUint32, new(windows.Overlapped)); err != nil {
+		_ = f.Close()
+
+		return nil, err
+	}
+
+	return f, nil
+}
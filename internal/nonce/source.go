@@ -0,0 +1,134 @@
+// Package nonce provides api.NonceSources that never reuse a nonce.
+package nonce
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var _ api.NonceSource = (*Source)(nil)
+
+// maxAttempts limits the number of nonces that are generated before giving
+// up.  Random nonces are only rejected if the random number generator is
+// broken while derived nonces are rejected when the counter has been
+// reset (e.g. by deleting the store.)
+const maxAttempts = 64
+
+// ErrNoUnusedNonce is returned when a Source repeatedly generates nonces
+// that have already been used.
+var ErrNoUnusedNonce = errors.New("unable to find an unused nonce")
+
+// Checker reports whether a nonce has already been used on-chain (e.g.
+// using ERC-3009's authorizationState method.)
+type Checker func(ctx context.Context, scope api.NonceScope, nonce []byte) (bool, error)
+
+// Source is an api.NonceSource that optionally checks that each nonce it
+// returns hasn't been used on-chain.  Nonces are either random or derived
+// from a seed and a per-scope counter that's kept in a Store.  Random
+// nonces are 256 bits so they're never recorded.
+type Source struct {
+	store Store
+	seed  []byte
+	check Checker
+}
+
+// Option configures a Source.
+type Option func(*Source)
+
+// WithSeed derives nonces from the seed and a counter rather than
+// generating them randomly.  The same seed and counter always produce the
+// same nonce so a persistent Store is needed to avoid reusing nonces
+// after a restart.
+func WithSeed(seed []byte) Option {
+	return func(s *Source) {
+		s.seed = append([]byte{}, seed...)
+	}
+}
+
+// WithChecker checks that each nonce hasn't been used on-chain before
+// it's returned.
+func WithChecker(check Checker) Option {
+	return func(s *Source) {
+		s.check = check
+	}
+}
+
+// NewSource returns a Source that keeps its counters in the store.  If
+// store is nil, counters are only remembered until the process exits.
+func NewSource(store Store, opts ...Option) *Source {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	s := &Source{
+		store: store,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Nonce implements api.NonceSource.
+func (s *Source) Nonce(ctx context.Context, scope api.NonceScope) ([]byte, error) {
+	for range maxAttempts {
+		nonce, err := s.generate(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.check != nil {
+			used, err := s.check(ctx, scope, nonce)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check nonce: %w", err)
+			}
+
+			if used {
+				continue
+			}
+		}
+
+		if s.seed == nil {
+			return nonce, nil
+		}
+
+		switch err := s.store.Use(scope, nonce); {
+		case errors.Is(err, ErrNonceUsed):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		return nonce, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoUnusedNonce, scope)
+}
+
+func (s *Source) generate(ctx context.Context, scope api.NonceScope) ([]byte, error) {
+	if s.seed == nil {
+		return api.RandomNonce{}.Nonce(ctx, scope)
+	}
+
+	counter, err := s.store.Next(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Including the scope means that a seed can be shared by accounts,
+	// tokens and chains without their nonces being predictable from one
+	// another.
+	return crypto.Keccak256(
+		s.seed,
+		[]byte(scope.String()),
+		binary.BigEndian.AppendUint64(nil, counter),
+	), nil
+}
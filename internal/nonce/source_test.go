@@ -0,0 +1,145 @@
+package nonce_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/nonce"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var scope = api.NonceScope{
+	ChainID:  big.NewInt(84532),
+	Contract: common.HexToAddress("0x036CbD53842c5426634e7929541eC2318f3dCF7e"),
+	Account:  common.HexToAddress("0x7840586eE7C215aE14599655b7c96ce23B7A9662"),
+}
+
+func TestSource(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("passes - random nonces", func(t *testing.T) {
+		t.Parallel()
+
+		source := nonce.NewSource(nil)
+
+		first, err := source.Nonce(ctx, scope)
+		require.NoError(t, err)
+		assert.Len(t, first, api.NonceSize)
+
+		second, err := source.Nonce(ctx, scope)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("passes - derived nonces are deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		first, err := nonce.NewSource(nil, nonce.WithSeed([]byte("seed"))).Nonce(ctx, scope)
+		require.NoError(t, err)
+
+		second, err := nonce.NewSource(nil, nonce.WithSeed([]byte("seed"))).Nonce(ctx, scope)
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+
+		other := scope
+		other.ChainID = big.NewInt(8453)
+
+		third, err := nonce.NewSource(nil, nonce.WithSeed([]byte("seed"))).Nonce(ctx, other)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, third)
+	})
+
+	t.Run("passes - derived nonces persist across restarts", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "x402-buyer", "nonces.json")
+
+		store, err := nonce.NewFileStore(path)
+		require.NoError(t, err)
+
+		first, err := nonce.NewSource(store, nonce.WithSeed([]byte("seed"))).Nonce(ctx, scope)
+		require.NoError(t, err)
+
+		store, err = nonce.NewFileStore(path)
+		require.NoError(t, err)
+
+		second, err := nonce.NewSource(store, nonce.WithSeed([]byte("seed"))).Nonce(ctx, scope)
+		require.NoError(t, err)
+		assert.NotEqual(t, first, second)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), hex.EncodeToString(first))
+	})
+
+	t.Run("passes - random nonces aren't persisted", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "x402-buyer", "nonces.json")
+
+		store, err := nonce.NewFileStore(path)
+		require.NoError(t, err)
+
+		_, err = nonce.NewSource(store).Nonce(ctx, scope)
+		require.NoError(t, err)
+
+		_, err = os.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("passes - nonces used on-chain are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		used, err := nonce.NewSource(nil, nonce.WithSeed([]byte("seed"))).Nonce(ctx, scope)
+		require.NoError(t, err)
+
+		var checked int
+
+		source := nonce.NewSource(nil, nonce.WithSeed([]byte("seed")), nonce.WithChecker(func(_ context.Context, _ api.NonceScope, n []byte) (bool, error) {
+			checked++
+
+			return bytes.Equal(n, used), nil
+		}))
+
+		actual, err := source.Nonce(ctx, scope)
+		require.NoError(t, err)
+		assert.NotEqual(t, used, actual)
+		assert.Equal(t, 2, checked)
+	})
+
+	t.Run("fails - on-chain check failed", func(t *testing.T) {
+		t.Parallel()
+
+		errRPC := errors.New("connection refused")
+
+		source := nonce.NewSource(nil, nonce.WithChecker(func(context.Context, api.NonceScope, []byte) (bool, error) {
+			return false, errRPC
+		}))
+
+		_, err := source.Nonce(ctx, scope)
+		require.ErrorIs(t, err, errRPC)
+	})
+
+	t.Run("fails - every nonce used", func(t *testing.T) {
+		t.Parallel()
+
+		source := nonce.NewSource(nil, nonce.WithChecker(func(context.Context, api.NonceScope, []byte) (bool, error) {
+			return true, nil
+		}))
+
+		_, err := source.Nonce(ctx, scope)
+		require.ErrorIs(t, err, nonce.ErrNoUnusedNonce)
+	})
+}
@@ -0,0 +1,259 @@
+package nonce
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// ErrNonceUsed is returned by a Store when a nonce has already been used.
+var ErrNonceUsed = errors.New("nonce already used")
+
+// A Store remembers the counters used to derive nonces and the derived
+// nonces that have been used so that they're never reused.
+type Store interface {
+	// Use records that the nonce has been used within the scope or
+	// returns ErrNonceUsed if it was already recorded.
+	Use(scope api.NonceScope, nonce []byte) error
+	// Next increments and returns the scope's counter.  Counters start
+	// at zero so the first call returns one.
+	Next(scope api.NonceScope) (uint64, error)
+}
+
+// state is the contents of a Store.  Only the counters are persisted by a
+// FileStore since a derived nonce is never repeated once its counter has
+// been recorded.
+type state struct {
+	Used     map[string]map[string]struct{} `json:"-"`
+	Counters map[string]uint64              `json:"counters"`
+}
+
+func newState() state {
+	return state{
+		Used:     map[string]map[string]struct{}{},
+		Counters: map[string]uint64{},
+	}
+}
+
+func (s *state) use(scope api.NonceScope, nonce []byte) error {
+	key := scope.String()
+
+	used, ok := s.Used[key]
+	if !ok {
+		used = map[string]struct{}{}
+		s.Used[key] = used
+	}
+
+	value := hex.EncodeToString(nonce)
+	if _, ok := used[value]; ok {
+		return fmt.Errorf("%w: 0x%s", ErrNonceUsed, value)
+	}
+
+	used[value] = struct{}{}
+
+	return nil
+}
+
+func (s *state) next(scope api.NonceScope) uint64 {
+	key := scope.String()
+	s.Counters[key]++
+
+	return s.Counters[key]
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*FileStore)(nil)
+)
+
+// MemoryStore is a Store that forgets the nonces that have been used when
+// the process exits.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state state
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		state: newState(),
+	}
+}
+
+// Use implements Store.
+func (m *MemoryStore) Use(scope api.NonceScope, nonce []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.use(scope, nonce)
+}
+
+// Next implements Store.
+func (m *MemoryStore) Next(scope api.NonceScope) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.state.next(scope), nil
+}
+
+// FileStore is a Store that persists the counters used to derive nonces
+// to a JSON file.  Each time a counter is incremented, the file is locked,
+// its counters are re-read and it's rewritten so that derived nonces
+// aren't repeated after a restart or by other processes sharing the file.
+// Used nonces are only remembered until the process exits.
+type FileStore struct {
+	mu    sync.Mutex
+	path  string
+	state state
+}
+
+// fileStores holds the FileStores opened by this process so that there's
+// only one for each file.
+var fileStores = struct {
+	mu     sync.Mutex
+	stores map[string]*FileStore
+}{
+	stores: map[string]*FileStore{},
+}
+
+// NewFileStore opens (or creates) the FileStore at path.  Opening the same
+// file more than once returns the same FileStore.
+func NewFileStore(path string) (*FileStore, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileStores.mu.Lock()
+	defer fileStores.mu.Unlock()
+
+	if f, ok := fileStores.stores[path]; ok {
+		return f, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	f := &FileStore{
+		path:  path,
+		state: newState(),
+	}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	fileStores.stores[path] = f
+
+	return f, nil
+}
+
+// DefaultFileStorePath returns the path of the FileStore used when no
+// path is provided (nonces.json in the user's x402-buyer configuration
+// directory.)
+func DefaultFileStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "x402-buyer", "nonces.json"), nil
+}
+
+// Use implements Store.
+func (f *FileStore) Use(scope api.NonceScope, nonce []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.state.use(scope, nonce)
+}
+
+// Next implements Store.
+func (f *FileStore) Next(scope api.NonceScope) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lock, err := lockFile(f.path + ".lock")
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock nonce store %s: %w", f.path, err)
+	}
+
+	defer func() { _ = lock.Close() }()
+
+	// Another process might have incremented the counter.
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+
+	counter := f.state.next(scope)
+
+	return counter, f.save()
+}
+
+// load reads the counters from the file.  Counters are only ever
+// increased so that a counter is never repeated, even if the file is
+// removed.
+func (f *FileStore) load() error {
+	data, err := os.ReadFile(f.path)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	var saved state
+
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid nonce store %s: %w", f.path, err)
+	}
+
+	for key, counter := range saved.Counters {
+		if counter > f.state.Counters[key] {
+			f.state.Counters[key] = counter
+		}
+	}
+
+	return nil
+}
+
+// save atomically replaces the file so that a crash can't leave it
+// partially written.
+func (f *FileStore) save() error {
+	data, err := json.Marshal(f.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
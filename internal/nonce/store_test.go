@@ -0,0 +1,71 @@
+package nonce_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/nonce"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes - one store per file", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		first, err := nonce.NewFileStore(filepath.Join(dir, "x402-buyer", "nonces.json"))
+		require.NoError(t, err)
+
+		second, err := nonce.NewFileStore(filepath.Join(dir, "x402-buyer", "..", "x402-buyer", "nonces.json"))
+		require.NoError(t, err)
+		assert.Same(t, first, second)
+
+		counter, err := first.Next(scope)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), counter)
+
+		counter, err = second.Next(scope)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), counter)
+	})
+
+	t.Run("passes - counters incremented by another process are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "x402-buyer", "nonces.json")
+
+		store, err := nonce.NewFileStore(path)
+		require.NoError(t, err)
+
+		counter, err := store.Next(scope)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), counter)
+
+		data, err := json.Marshal(map[string]any{
+			"counters": map[string]uint64{scope.String(): 5},
+		})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		counter, err = store.Next(scope)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(6), counter)
+	})
+
+	t.Run("fails - invalid file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nonces.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		_, err := nonce.NewFileStore(path)
+		require.ErrorContains(t, err, "invalid nonce store")
+	})
+}
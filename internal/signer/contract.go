@@ -0,0 +1,121 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var (
+	_ api.ContractSigner = (*SmartWalletSigner)(nil)
+	_ api.ContextSigner  = (*SmartWalletSigner)(nil)
+)
+
+// ERC6492MagicSuffix is appended to signatures wrapped as described by
+// ERC-6492 so that verifiers can detect them.
+var ERC6492MagicSuffix = hexutil.MustDecode("0x6492649264926492649264926492649264926492649264926492649264926492")
+
+// erc6492Arguments is the ABI encoding of an ERC-6492 wrapped signature's
+// (factory, factoryCalldata, signature) tuple.
+var erc6492Arguments = func() abi.Arguments {
+	address, _ := abi.NewType("address", "", nil)
+	bytes, _ := abi.NewType("bytes", "", nil)
+
+	return abi.Arguments{{Type: address}, {Type: bytes}, {Type: bytes}}
+}()
+
+// SmartWalletSigner is an api.ContractSigner that signs on behalf of a
+// smart-contract wallet using the key of one of the wallet's owners.  The
+// owner's signature is validated by the wallet's EIP-1271
+// isValidSignature method.
+//
+// Wallets that haven't been deployed yet (counterfactual wallets) can't
+// validate signatures so their signatures are wrapped as described by
+// ERC-6492 along with the factory call that deploys the wallet.
+type SmartWalletSigner struct {
+	wallet          common.Address
+	owner           api.Signer
+	factory         common.Address
+	factoryCalldata []byte
+}
+
+// NewSmartWalletSigner returns a SmartWalletSigner for a deployed wallet.
+func NewSmartWalletSigner(wallet common.Address, owner api.Signer) (*SmartWalletSigner, error) {
+	if wallet == (common.Address{}) {
+		return nil, errors.New("smart wallet address is required")
+	}
+
+	if owner == nil {
+		return nil, errors.New("smart wallet owner is required")
+	}
+
+	return &SmartWalletSigner{
+		wallet: wallet,
+		owner:  owner,
+	}, nil
+}
+
+// NewCounterfactualWalletSigner returns a SmartWalletSigner for a wallet
+// that will be deployed by calling the factory with factoryCalldata.
+func NewCounterfactualWalletSigner(wallet common.Address, owner api.Signer, factory common.Address, factoryCalldata []byte) (*SmartWalletSigner, error) {
+	if factory == (common.Address{}) || len(factoryCalldata) == 0 {
+		return nil, errors.New("counterfactual wallet requires a factory address and calldata")
+	}
+
+	s, err := NewSmartWalletSigner(wallet, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	s.factory = factory
+	s.factoryCalldata = bytes.Clone(factoryCalldata)
+
+	return s, nil
+}
+
+// Address implements api.EVMSigner and returns the wallet's address.
+func (s *SmartWalletSigner) Address() common.Address {
+	return s.wallet
+}
+
+// IsContract implements api.ContractSigner.
+func (s *SmartWalletSigner) IsContract() bool {
+	return true
+}
+
+// Sign implements api.Signer.
+func (s *SmartWalletSigner) Sign(digestHash []byte) ([]byte, error) {
+	return s.SignContext(context.Background(), digestHash)
+}
+
+// SignContext implements api.ContextSigner.
+func (s *SmartWalletSigner) SignContext(ctx context.Context, digestHash []byte) ([]byte, error) {
+	sig, err := api.SignContext(ctx, s.owner, digestHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// EIP-1271 wallets validate owner signatures using ecrecover which
+	// expects the recovery ID to be 27 or 28.
+	if !api.IsContractSigner(s.owner) && len(sig) == 65 && sig[64] < 27 {
+		sig[64] += 27
+	}
+
+	if s.factoryCalldata == nil {
+		return sig, nil
+	}
+
+	wrapped, err := erc6492Arguments.Pack(s.factory, s.factoryCalldata, sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap ERC-6492 signature: %w", err)
+	}
+
+	return append(wrapped, ERC6492MagicSuffix...), nil
+}
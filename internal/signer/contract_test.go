@@ -0,0 +1,87 @@
+package signer_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestSmartWalletSigner(t *testing.T) {
+	t.Parallel()
+
+	const expSig = "4134c5a9c223b337acaa8085bb4553787fa159a809793f6920044766d55271b77eb01e102b9525edffcac69c31a4c1d51c7fee78bab28bd716f3bb5181ed31001b"
+
+	wallet := common.HexToAddress("0xCA11bde05977b3631167028862bE2a173976CA11")
+	factory := common.HexToAddress("0x0BA5ED0c6AA8c49038F819E587E2633c4A9F428a")
+	factoryCalldata := []byte{0x3f, 0xfb, 0xa3, 0x6f}
+
+	owner, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	hash, _ := apitest.TransferWithAuthorizationHash(t)
+
+	t.Run("passes - deployed wallet", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewSmartWalletSigner(wallet, owner)
+		require.NoError(t, err)
+		assert.Equal(t, wallet, s.Address())
+		assert.True(t, api.IsContractSigner(s))
+
+		sig, err := s.Sign(hash)
+		require.NoError(t, err)
+		assert.Equal(t, expSig, hex.EncodeToString(sig))
+	})
+
+	t.Run("passes - counterfactual wallet", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewCounterfactualWalletSigner(wallet, owner, factory, factoryCalldata)
+		require.NoError(t, err)
+
+		sig, err := s.Sign(hash)
+		require.NoError(t, err)
+
+		wrapped, ok := bytes.CutSuffix(sig, signer.ERC6492MagicSuffix)
+		require.True(t, ok)
+
+		address, _ := abi.NewType("address", "", nil)
+		bytes, _ := abi.NewType("bytes", "", nil)
+
+		values, err := abi.Arguments{{Type: address}, {Type: bytes}, {Type: bytes}}.Unpack(wrapped)
+		require.NoError(t, err)
+		require.Len(t, values, 3)
+		assert.Equal(t, factory, values[0])
+		assert.Equal(t, factoryCalldata, values[1])
+		assert.Equal(t, expSig, hex.EncodeToString(values[2].([]byte)))
+	})
+
+	t.Run("fails - missing wallet", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewSmartWalletSigner(common.Address{}, owner)
+		require.Error(t, err)
+	})
+
+	t.Run("fails - missing factory", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewCounterfactualWalletSigner(wallet, owner, common.Address{}, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("passes - EOA is not a contract signer", func(t *testing.T) {
+		t.Parallel()
+
+		assert.False(t, api.IsContractSigner(owner))
+	})
+}
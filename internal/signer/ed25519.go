@@ -0,0 +1,90 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/selesy/x402-buyer/internal/svm"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var _ api.SVMSigner = (*Ed25519Signer)(nil)
+
+// Ed25519Signer is an api.SVMSigner that signs Solana transactions using
+// an ed25519.PrivateKey.
+type Ed25519Signer struct {
+	priv ed25519.PrivateKey
+}
+
+func NewEd25519Signer(priv ed25519.PrivateKey) (*Ed25519Signer, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidKeyLength, len(priv))
+	}
+
+	// A Solana keypair is the seed followed by the public key so a
+	// corrupted keypair can be detected by deriving the public key again.
+	if derived := ed25519.NewKeyFromSeed(priv.Seed()); !bytes.Equal(derived, priv) {
+		return nil, ErrInvalidKeypair
+	}
+
+	return &Ed25519Signer{
+		priv: priv,
+	}, nil
+}
+
+// NewEd25519SignerFromBytes accepts either a 32-byte seed or a 64-byte
+// Solana keypair.
+func NewEd25519SignerFromBytes(b []byte) (*Ed25519Signer, error) {
+	if len(b) == ed25519.SeedSize {
+		return NewEd25519Signer(ed25519.NewKeyFromSeed(b))
+	}
+
+	return NewEd25519Signer(ed25519.PrivateKey(bytes.Clone(b)))
+}
+
+// NewEd25519SignerFromBase58 parses the base58-encoded keypair exported by
+// Solana wallets.
+func NewEd25519SignerFromBase58(s string) (*Ed25519Signer, error) {
+	b, err := svm.DecodeBase58(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEd25519SignerFromBytes(b)
+}
+
+func NewEd25519SignerFromEnv(name string) (*Ed25519Signer, error) {
+	privBase58 := os.Getenv(name)
+	if privBase58 == "" {
+		return nil, fmt.Errorf("%w: %s", ErrEnvVarNotFound, name)
+	}
+
+	return NewEd25519SignerFromBase58(privBase58)
+}
+
+// NewEd25519SignerFromKeypairFile reads a keypair file written by the
+// Solana CLI (a JSON array of the keypair's 64 bytes.)
+func NewEd25519SignerFromKeypairFile(path string) (*Ed25519Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keypair []byte
+	if err := json.Unmarshal(data, &keypair); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeypair, err)
+	}
+
+	return NewEd25519SignerFromBytes(keypair)
+}
+
+func (s *Ed25519Signer) PublicKey() ed25519.PublicKey {
+	return s.priv.Public().(ed25519.PublicKey)
+}
+
+func (s *Ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.priv, message), nil
+}
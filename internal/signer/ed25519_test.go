@@ -0,0 +1,99 @@
+package signer_test
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/internal/svm"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestEd25519Signer(t *testing.T) {
+	t.Parallel()
+
+	const expPub = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+
+	seed, err := hex.DecodeString(apitest.Ed25519SeedHex)
+	require.NoError(t, err)
+
+	priv := ed25519.NewKeyFromSeed(seed)
+
+	t.Run("passes - private key", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewEd25519Signer(priv)
+		require.NoError(t, err)
+		assert.Equal(t, expPub, hex.EncodeToString(s.PublicKey()))
+
+		sig, err := s.Sign([]byte("message"))
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(s.PublicKey(), []byte("message"), sig))
+	})
+
+	t.Run("passes - seed", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewEd25519SignerFromBytes(seed)
+		require.NoError(t, err)
+		assert.Equal(t, expPub, hex.EncodeToString(s.PublicKey()))
+	})
+
+	t.Run("passes - base58 keypair", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewEd25519SignerFromBase58(svm.EncodeBase58(priv))
+		require.NoError(t, err)
+		assert.Equal(t, expPub, hex.EncodeToString(s.PublicKey()))
+	})
+
+	t.Run("passes - keypair file", func(t *testing.T) {
+		t.Parallel()
+
+		numbers := make([]int, len(priv))
+		for i, b := range priv {
+			numbers[i] = int(b)
+		}
+
+		data, err := json.Marshal(numbers)
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "id.json")
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		s, err := signer.NewEd25519SignerFromKeypairFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, expPub, hex.EncodeToString(s.PublicKey()))
+	})
+
+	t.Run("fails - invalid key length", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewEd25519SignerFromBytes(seed[:31])
+		require.ErrorIs(t, err, signer.ErrInvalidKeyLength)
+	})
+
+	t.Run("fails - mismatched public key", func(t *testing.T) {
+		t.Parallel()
+
+		keypair := append([]byte{}, priv...)
+		keypair[63] ^= 0xff
+
+		_, err := signer.NewEd25519SignerFromBytes(keypair)
+		require.ErrorIs(t, err, signer.ErrInvalidKeypair)
+	})
+
+	t.Run("fails - missing environment variable", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewEd25519SignerFromEnv("X402_BUYER_MISSING_SOLANA_KEY")
+		require.ErrorIs(t, err, signer.ErrEnvVarNotFound)
+	})
+}
@@ -17,3 +17,11 @@ var ErrInvalidCurve = errors.New("curve must be secp256k1 curve from go-ethereum
 // ErrInvalidPoint is returned if the X, Y coordinates of the provided point
 // are not on the secp256k1 curve.
 var ErrInvalidPoint = errors.New("point coordinates must be on the secp256k1 curve")
+
+// ErrInvalidKeyLength is returned when an Ed25519 private key is neither a
+// 32-byte seed nor a 64-byte keypair.
+var ErrInvalidKeyLength = errors.New("Ed25519 private key must be a 32-byte seed or 64-byte keypair")
+
+// ErrInvalidKeypair is returned when the public key half of an Ed25519
+// keypair doesn't match its private key.
+var ErrInvalidKeypair = errors.New("invalid Ed25519 keypair")
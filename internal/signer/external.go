@@ -0,0 +1,93 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var _ api.TypedDataSigner = (*ExternalSigner)(nil)
+
+// ErrDigestSigningUnsupported is returned when an ExternalSigner or a
+// WalletSigner is asked to sign a bare digest.  They only sign data they
+// can show to their user, such as EIP-712 typed data.
+var ErrDigestSigningUnsupported = errors.New("signer can't sign a bare digest")
+
+// ErrExternalAccountNotFound is returned if the account passed to
+// NewExternalSigner isn't managed by the external signer.
+var ErrExternalAccountNotFound = errors.New("account not managed by external signer")
+
+// ExternalSigner is an api.EVMSigner that delegates signing to an external
+// signer (such as Clef) using its JSON-RPC API.  The private key never
+// enters the buyer's process and the external signer receives the full
+// EIP-712 typed data of each payment rather than its digest.
+type ExternalSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewExternalSigner connects to the external signer at endpoint, which is
+// either an HTTP(S) URL or the path of an IPC socket (optionally prefixed
+// with unix://.)  If address is the zero address, the first account listed
+// by the external signer is used.
+func NewExternalSigner(ctx context.Context, endpoint string, address common.Address) (*ExternalSigner, error) {
+	client, err := rpc.DialContext(ctx, strings.TrimPrefix(endpoint, "unix://"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer: %w", err)
+	}
+
+	var accounts []common.Address
+	if err := client.CallContext(ctx, &accounts, "account_list"); err != nil {
+		client.Close()
+
+		return nil, fmt.Errorf("failed to list external signer accounts: %w", err)
+	}
+
+	for _, account := range accounts {
+		if address == (common.Address{}) || account == address {
+			return &ExternalSigner{
+				client:  client,
+				address: account,
+			}, nil
+		}
+	}
+
+	client.Close()
+
+	return nil, fmt.Errorf("%w: %s", ErrExternalAccountNotFound, address.Hex())
+}
+
+func (s *ExternalSigner) Address() common.Address {
+	return s.address
+}
+
+// Sign implements api.Signer but always fails with
+// ErrDigestSigningUnsupported.
+func (s *ExternalSigner) Sign(_ []byte) ([]byte, error) {
+	return nil, ErrDigestSigningUnsupported
+}
+
+// SignTypedData asks the external signer to sign the EIP-712 typed data
+// using its account_signTypedData method.  The returned signature's V is
+// 27 or 28.
+func (s *ExternalSigner) SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.client.CallContext(ctx, &sig, "account_signTypedData", common.NewMixedcaseAddress(s.address), typedData); err != nil {
+		return nil, fmt.Errorf("external signer refused to sign: %w", err)
+	}
+
+	return sig, nil
+}
+
+// Close disconnects from the external signer.
+func (s *ExternalSigner) Close() {
+	s.client.Close()
+}
@@ -0,0 +1,64 @@
+package signer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestExternalSigner(t *testing.T) {
+	t.Parallel()
+
+	srv := apitest.NewExternalSigner(t)
+	address := crypto.PubkeyToAddress(apitest.PrivateKey(t).PublicKey)
+
+	t.Run("passes - account", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewExternalSigner(context.Background(), srv.URL, address)
+		require.NoError(t, err)
+
+		t.Cleanup(s.Close)
+
+		assert.Equal(t, address, s.Address())
+	})
+
+	t.Run("passes - first account", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewExternalSigner(context.Background(), srv.URL, common.Address{})
+		require.NoError(t, err)
+
+		t.Cleanup(s.Close)
+
+		assert.Equal(t, address, s.Address())
+	})
+
+	t.Run("fails - digest signing", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewExternalSigner(context.Background(), srv.URL, address)
+		require.NoError(t, err)
+
+		t.Cleanup(s.Close)
+
+		hash, _ := apitest.TransferWithAuthorizationHash(t)
+
+		_, err = s.Sign(hash)
+		require.ErrorIs(t, err, signer.ErrDigestSigningUnsupported)
+	})
+
+	t.Run("fails - unknown account", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewExternalSigner(context.Background(), srv.URL, common.HexToAddress("0x60ac86571E55F9735F00cE9e28361d203977B260"))
+		require.ErrorIs(t, err, signer.ErrExternalAccountNotFound)
+	})
+}
@@ -0,0 +1,153 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ErrInvalidMnemonic is returned when a mnemonic isn't a valid BIP-39
+// mnemonic (e.g. a word is misspelled or the checksum doesn't match.)
+var ErrInvalidMnemonic = errors.New("invalid BIP-39 mnemonic")
+
+// ErrInvalidDerivationPath is returned when a BIP-32 derivation path
+// can't be parsed or derives an invalid key.
+var ErrInvalidDerivationPath = errors.New("invalid derivation path")
+
+// HDWallet derives the accounts of a BIP-32 hierarchical deterministic
+// wallet from the seed of a BIP-39 mnemonic.
+type HDWallet struct {
+	master extendedKey
+}
+
+// extendedKey is a BIP-32 extended private key.
+type extendedKey struct {
+	key       []byte
+	chainCode []byte
+}
+
+func NewHDWallet(mnemonic, passphrase string) (*HDWallet, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMnemonic, err)
+	}
+
+	return NewHDWalletFromSeed(seed)
+}
+
+func NewHDWalletFromSeed(seed []byte) (*HDWallet, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	_, _ = mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	if !validKey(sum[:32]) {
+		return nil, errors.New("seed produces an invalid master key")
+	}
+
+	return &HDWallet{
+		master: extendedKey{
+			key:       sum[:32],
+			chainCode: sum[32:],
+		},
+	}, nil
+}
+
+// Derive returns a signer for the account at the derivation path.
+func (w *HDWallet) Derive(path accounts.DerivationPath) (*ECDSASigner, error) {
+	key := w.master
+
+	for _, index := range path {
+		var err error
+
+		if key, err = key.child(index); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidDerivationPath, path, err)
+		}
+	}
+
+	return NewECDSASignerFromBytes(key.key)
+}
+
+// DeriveAccount returns a signer for the account at index using the
+// standard Ethereum derivation path (m/44'/60'/0'/0/index.)
+func (w *HDWallet) DeriveAccount(index uint32) (*ECDSASigner, error) {
+	path := make(accounts.DerivationPath, len(accounts.DefaultRootDerivationPath))
+	copy(path, accounts.DefaultRootDerivationPath)
+
+	return w.Derive(append(path, index))
+}
+
+// NewHDSigner returns a signer for the account at the derivation path
+// (e.g. m/44'/60'/0'/0/0) of the wallet with the BIP-39 mnemonic.  If path
+// is empty, the first account of the standard Ethereum derivation path is
+// used.
+func NewHDSigner(mnemonic, passphrase, path string) (*ECDSASigner, error) {
+	if path == "" {
+		path = accounts.DefaultBaseDerivationPath.String()
+	}
+
+	derivationPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDerivationPath, err)
+	}
+
+	wallet, err := NewHDWallet(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet.Derive(derivationPath)
+}
+
+// child implements BIP-32's CKDpriv function.
+func (k extendedKey) child(index uint32) (extendedKey, error) {
+	var data []byte
+
+	if index >= 0x80000000 {
+		data = append([]byte{0}, k.key...)
+	} else {
+		priv, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return extendedKey{}, err
+		}
+
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	_, _ = mac.Write(binary.BigEndian.AppendUint32(data, index))
+	sum := mac.Sum(nil)
+
+	if !validKey(sum[:32]) {
+		return extendedKey{}, errors.New("invalid child key")
+	}
+
+	n := secp256k1.S256().Params().N
+
+	child := new(big.Int).SetBytes(sum[:32])
+	child.Add(child, new(big.Int).SetBytes(k.key))
+	child.Mod(child, n)
+
+	if child.Sign() == 0 {
+		return extendedKey{}, errors.New("invalid child key")
+	}
+
+	return extendedKey{
+		key:       child.FillBytes(make([]byte, 32)),
+		chainCode: sum[32:],
+	}, nil
+}
+
+// validKey returns true if the key is a valid secp256k1 private key.
+func validKey(key []byte) bool {
+	k := new(big.Int).SetBytes(key)
+
+	return k.Sign() > 0 && k.Cmp(secp256k1.S256().Params().N) < 0
+}
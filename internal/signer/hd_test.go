@@ -0,0 +1,93 @@
+package signer_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/signer"
+)
+
+// testMnemonic is the well-known development mnemonic used by Hardhat and
+// Anvil.
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestHDWallet(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes - BIP-32 test vector 1", func(t *testing.T) {
+		t.Parallel()
+
+		seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+		require.NoError(t, err)
+
+		wallet, err := signer.NewHDWalletFromSeed(seed)
+		require.NoError(t, err)
+
+		path, err := accounts.ParseDerivationPath("m/0'/1/2'/2/1000000000")
+		require.NoError(t, err)
+
+		s, err := wallet.Derive(path)
+		require.NoError(t, err)
+
+		priv, err := crypto.HexToECDSA("471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8")
+		require.NoError(t, err)
+		assert.Equal(t, crypto.PubkeyToAddress(priv.PublicKey), s.Address())
+	})
+
+	t.Run("passes - several accounts from one mnemonic", func(t *testing.T) {
+		t.Parallel()
+
+		wallet, err := signer.NewHDWallet(testMnemonic, "")
+		require.NoError(t, err)
+
+		for index, expected := range []string{
+			"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+			"0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+			"0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		} {
+			s, err := wallet.DeriveAccount(uint32(index))
+			require.NoError(t, err)
+			assert.Equal(t, common.HexToAddress(expected), s.Address())
+		}
+	})
+
+	t.Run("passes - derivation path", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewHDSigner(testMnemonic, "", "m/44'/60'/0'/0/1")
+		require.NoError(t, err)
+		assert.Equal(t, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), s.Address())
+
+		s, err = signer.NewHDSigner(testMnemonic, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"), s.Address())
+	})
+
+	t.Run("passes - passphrase changes accounts", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := signer.NewHDSigner(testMnemonic, "TREZOR", "")
+		require.NoError(t, err)
+		assert.NotEqual(t, common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"), s.Address())
+	})
+
+	t.Run("fails - invalid checksum", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewHDSigner("test test test test test test test test test test test test", "", "")
+		require.ErrorIs(t, err, signer.ErrInvalidMnemonic)
+	})
+
+	t.Run("fails - invalid derivation path", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := signer.NewHDSigner(testMnemonic, "", "m/44'/sixty'/0'/0/0")
+		require.ErrorIs(t, err, signer.ErrInvalidDerivationPath)
+	})
+}
@@ -0,0 +1,95 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var _ api.TypedDataSigner = (*WalletSigner)(nil)
+
+// ErrWalletAccountNotFound is returned if the account passed to
+// NewWalletSigner isn't contained in the wallet.
+var ErrWalletAccountNotFound = errors.New("account not found in wallet")
+
+// WalletSigner is an api.EVMSigner that signs using any go-ethereum
+// accounts.Wallet (e.g. a keystore or hardware wallet.)
+//
+// Wallets don't sign bare digests so Sign always fails and SignTypedData
+// gives the wallet the EIP-712 encoded data (0x19 0x01 domainSeparator
+// structHash) with the typed data MIME type, which hardware wallets use to
+// display what's being signed.
+// Wallets' SignText methods aren't used since token contracts don't accept
+// the resulting personal_sign signatures.
+type WalletSigner struct {
+	wallet accounts.Wallet
+	acct   accounts.Account
+	pass   []byte
+}
+
+// NewWalletSigner returns a WalletSigner for the wallet's account.  If
+// pass is nil, the account must already be unlocked (or the wallet must
+// be able to sign without a passphrase); otherwise the passphrase is used
+// to unlock the account for each signature.
+func NewWalletSigner(wallet accounts.Wallet, acct accounts.Account, pass []byte) (*WalletSigner, error) {
+	if !wallet.Contains(acct) {
+		return nil, fmt.Errorf("%w: %s", ErrWalletAccountNotFound, acct.Address.Hex())
+	}
+
+	return &WalletSigner{
+		wallet: wallet,
+		acct:   acct,
+		pass:   pass,
+	}, nil
+}
+
+func (s *WalletSigner) Address() common.Address {
+	return s.acct.Address
+}
+
+// Sign implements api.Signer but always fails with
+// ErrDigestSigningUnsupported since wallets hash the data they sign.
+func (s *WalletSigner) Sign(_ []byte) ([]byte, error) {
+	return nil, ErrDigestSigningUnsupported
+}
+
+// SignTypedData signs the EIP-712 encoded typedData.  The returned
+// signature's V is 27 or 28.
+func (s *WalletSigner) SignTypedData(_ context.Context, typedData apitypes.TypedData) ([]byte, error) {
+	_, data, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := s.signData([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid wallet signature length: %d", len(sig))
+	}
+
+	// Hardware wallets already return V as 27 or 28.
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+
+	return sig, nil
+}
+
+// signData asks the wallet to sign the EIP-712 encoded data.
+func (s *WalletSigner) signData(data []byte) ([]byte, error) {
+	if s.pass != nil {
+		return s.wallet.SignDataWithPassphrase(s.acct, string(s.pass), accounts.MimetypeTypedData, data)
+	}
+
+	return s.wallet.SignData(s.acct, accounts.MimetypeTypedData, data)
+}
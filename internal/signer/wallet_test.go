@@ -0,0 +1,78 @@
+package signer_test
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestWalletSigner(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes - passphrase", func(t *testing.T) {
+		t.Parallel()
+
+		wallet, acct := apitest.Wallet(t)
+
+		signer, err := signer.NewWalletSigner(wallet, acct, []byte(apitest.Passphrase))
+		require.NoError(t, err)
+
+		apitest.TestTypedDataSigner(t, signer)
+	})
+
+	t.Run("passes - unlocked", func(t *testing.T) {
+		t.Parallel()
+
+		ks, acct := apitest.Keystore(t)
+		require.NoError(t, ks.Unlock(acct, apitest.Passphrase))
+
+		wallet, err := accounts.NewManager(&accounts.Config{}, ks).Find(acct)
+		require.NoError(t, err)
+
+		signer, err := signer.NewWalletSigner(wallet, acct, nil)
+		require.NoError(t, err)
+
+		apitest.TestTypedDataSigner(t, signer)
+	})
+
+	t.Run("fails - locked", func(t *testing.T) {
+		t.Parallel()
+
+		wallet, acct := apitest.Wallet(t)
+
+		signer, err := signer.NewWalletSigner(wallet, acct, nil)
+		require.NoError(t, err)
+
+		_, err = signer.SignTypedData(t.Context(), apitest.TransferWithAuthorizationTypedData(t))
+		require.ErrorIs(t, err, keystore.ErrLocked)
+	})
+
+	t.Run("fails - digest signing", func(t *testing.T) {
+		t.Parallel()
+
+		wallet, acct := apitest.Wallet(t)
+
+		s, err := signer.NewWalletSigner(wallet, acct, []byte(apitest.Passphrase))
+		require.NoError(t, err)
+
+		hash, _ := apitest.TransferWithAuthorizationHash(t)
+
+		_, err = s.Sign(hash)
+		require.ErrorIs(t, err, signer.ErrDigestSigningUnsupported)
+	})
+
+	t.Run("fails - account not in wallet", func(t *testing.T) {
+		t.Parallel()
+
+		wallet, _ := apitest.Wallet(t)
+
+		_, err := signer.NewWalletSigner(wallet, accounts.Account{Address: common.HexToAddress("0x60ac86571E55F9735F00cE9e28361d203977B260")}, nil)
+		require.ErrorIs(t, err, signer.ErrWalletAccountNotFound)
+	})
+}
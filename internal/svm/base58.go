@@ -0,0 +1,80 @@
+package svm
+
+import (
+	"errors"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var errInvalidBase58 = errors.New("invalid base58 encoding")
+
+var base58Indexes = func() [256]int {
+	var indexes [256]int
+	for i := range indexes {
+		indexes[i] = -1
+	}
+
+	for i, c := range base58Alphabet {
+		indexes[c] = i
+	}
+
+	return indexes
+}()
+
+// EncodeBase58 encodes data using the Bitcoin base58 alphabet used by
+// Solana for public keys, signatures and block hashes.
+func EncodeBase58(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	for range zeros {
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
+
+// DecodeBase58 decodes a string encoded using EncodeBase58.
+func DecodeBase58(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errInvalidBase58
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	n := new(big.Int)
+	radix := big.NewInt(58)
+
+	for i := range len(s) {
+		idx := base58Indexes[s[i]]
+		if idx < 0 {
+			return nil, errInvalidBase58
+		}
+
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
@@ -0,0 +1,91 @@
+// Package svm provides the primitives needed to build Solana transactions
+// without depending on a full Solana SDK.
+package svm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"filippo.io/edwards25519"
+)
+
+// PublicKeySize is the size of a Solana public key (or account address.)
+const PublicKeySize = 32
+
+// PublicKey is the address of a Solana account.
+type PublicKey [PublicKeySize]byte
+
+// Well-known program addresses.
+var (
+	SystemProgramID                 = MustPublicKey("11111111111111111111111111111111")
+	ComputeBudgetProgramID          = MustPublicKey("ComputeBudget111111111111111111111111111111")
+	TokenProgramID                  = MustPublicKey("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+	Token2022ProgramID              = MustPublicKey("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+	AssociatedTokenAccountProgramID = MustPublicKey("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL")
+)
+
+// ErrInvalidPublicKey is returned when a string isn't the base58 encoding
+// of a 32-byte public key.
+var ErrInvalidPublicKey = errors.New("invalid Solana public key")
+
+// ParsePublicKey decodes a base58-encoded public key.
+func ParsePublicKey(s string) (PublicKey, error) {
+	data, err := DecodeBase58(s)
+	if err != nil || len(data) != PublicKeySize {
+		return PublicKey{}, fmt.Errorf("%w: %q", ErrInvalidPublicKey, s)
+	}
+
+	return PublicKey(data), nil
+}
+
+// MustPublicKey is like ParsePublicKey but panics if s is invalid.  It's
+// intended for initializing well-known addresses.
+func MustPublicKey(s string) PublicKey {
+	key, err := ParsePublicKey(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+// String returns the base58 encoding of the public key.
+func (k PublicKey) String() string {
+	return EncodeBase58(k[:])
+}
+
+// FindProgramAddress derives the program derived address (PDA) for the
+// seeds and program along with its bump seed.
+func FindProgramAddress(seeds [][]byte, program PublicKey) (PublicKey, byte, error) {
+	for bump := 255; bump >= 0; bump-- {
+		h := sha256.New()
+		for _, seed := range seeds {
+			h.Write(seed)
+		}
+
+		h.Write([]byte{byte(bump)})
+		h.Write(program[:])
+		h.Write([]byte("ProgramDerivedAddress"))
+
+		var candidate PublicKey
+
+		copy(candidate[:], h.Sum(nil))
+
+		// Program derived addresses must not be valid Ed25519 public
+		// keys so that no private key can sign for them.
+		if _, err := new(edwards25519.Point).SetBytes(candidate[:]); err != nil {
+			return candidate, byte(bump), nil
+		}
+	}
+
+	return PublicKey{}, 0, errors.New("unable to find a program derived address")
+}
+
+// AssociatedTokenAddress returns the address of the owner's associated
+// token account for the mint.
+func AssociatedTokenAddress(owner, mint, tokenProgram PublicKey) (PublicKey, error) {
+	address, _, err := FindProgramAddress([][]byte{owner[:], tokenProgram[:], mint[:]}, AssociatedTokenAccountProgramID)
+
+	return address, err
+}
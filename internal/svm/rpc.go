@@ -0,0 +1,182 @@
+package svm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrAccountNotFound is returned when an account doesn't exist.
+var ErrAccountNotFound = errors.New("solana account not found")
+
+// ErrNotMint is returned when an account isn't an SPL token mint.
+var ErrNotMint = errors.New("account is not a token mint")
+
+// RPC is a minimal client for the Solana JSON-RPC API.
+type RPC struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewRPC returns an RPC client for the endpoint.  If client is nil,
+// http.DefaultClient is used.
+func NewRPC(endpoint string, client *http.Client) *RPC {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &RPC{
+		endpoint: endpoint,
+		client:   client,
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("solana rpc error %d: %s", e.Code, e.Message)
+}
+
+func (r *RPC) call(ctx context.Context, method string, result any, params ...any) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("solana rpc %s failed: %s", method, resp.Status)
+	}
+
+	var out struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("failed to decode solana rpc response: %w", err)
+	}
+
+	if out.Error != nil {
+		return out.Error
+	}
+
+	return json.Unmarshal(out.Result, result)
+}
+
+// LatestBlockhash returns the most recent block hash which must be included
+// in transactions so that they expire if they're not processed.
+func (r *RPC) LatestBlockhash(ctx context.Context) (Hash, error) {
+	var result struct {
+		Value struct {
+			Blockhash string `json:"blockhash"`
+		} `json:"value"`
+	}
+
+	if err := r.call(ctx, "getLatestBlockhash", &result, map[string]string{"commitment": "confirmed"}); err != nil {
+		return Hash{}, err
+	}
+
+	data, err := DecodeBase58(result.Value.Blockhash)
+	if err != nil || len(data) != HashSize {
+		return Hash{}, fmt.Errorf("invalid block hash: %q", result.Value.Blockhash)
+	}
+
+	return Hash(data), nil
+}
+
+// AccountInfo is the state of an on-chain account.
+type AccountInfo struct {
+	Owner PublicKey
+	Data  []byte
+}
+
+// AccountInfo returns the state of the account or an error if the account
+// doesn't exist.
+func (r *RPC) AccountInfo(ctx context.Context, account PublicKey) (*AccountInfo, error) {
+	var result struct {
+		Value *struct {
+			Owner string    `json:"owner"`
+			Data  [2]string `json:"data"`
+		} `json:"value"`
+	}
+
+	if err := r.call(ctx, "getAccountInfo", &result, account.String(), map[string]string{"encoding": "base64"}); err != nil {
+		return nil, err
+	}
+
+	if result.Value == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, account)
+	}
+
+	owner, err := ParsePublicKey(result.Value.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.Value.Data[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid account data: %w", err)
+	}
+
+	return &AccountInfo{
+		Owner: owner,
+		Data:  data,
+	}, nil
+}
+
+// mintDecimalsOffset is the offset of the decimals field in an SPL token
+// mint account (after the optional mint authority and supply.)
+const mintDecimalsOffset = 44
+
+// Mint returns the token program that owns the mint and the number of
+// decimals used by its token.  Both are needed to transfer the token using
+// TransferChecked.
+func (r *RPC) Mint(ctx context.Context, mint PublicKey) (PublicKey, uint8, error) {
+	info, err := r.AccountInfo(ctx, mint)
+	if err != nil {
+		return PublicKey{}, 0, err
+	}
+
+	if info.Owner != TokenProgramID && info.Owner != Token2022ProgramID {
+		return PublicKey{}, 0, fmt.Errorf("%w: %s is owned by %s", ErrNotMint, mint, info.Owner)
+	}
+
+	if len(info.Data) <= mintDecimalsOffset {
+		return PublicKey{}, 0, fmt.Errorf("%w: %s", ErrNotMint, mint)
+	}
+
+	return info.Owner, info.Data[mintDecimalsOffset], nil
+}
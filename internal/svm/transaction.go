@@ -0,0 +1,253 @@
+package svm
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// HashSize is the size of a Solana block hash.
+const HashSize = 32
+
+// Hash is a Solana block hash.
+type Hash [HashSize]byte
+
+// String returns the base58 encoding of the hash.
+func (h Hash) String() string {
+	return EncodeBase58(h[:])
+}
+
+// AccountMeta describes an account used by an Instruction.
+type AccountMeta struct {
+	PublicKey PublicKey
+	Signer    bool
+	Writable  bool
+}
+
+// Instruction is a call to an on-chain program.
+type Instruction struct {
+	ProgramID PublicKey
+	Accounts  []AccountMeta
+	Data      []byte
+}
+
+type compiledInstruction struct {
+	programIDIndex byte
+	accounts       []byte
+	data           []byte
+}
+
+// Message is a compiled version 0 transaction message.
+type Message struct {
+	numRequiredSignatures       byte
+	numReadonlySignedAccounts   byte
+	numReadonlyUnsignedAccounts byte
+
+	// AccountKeys lists every account used by the message's instructions
+	// with the fee payer first followed by the other signers.
+	AccountKeys     []PublicKey
+	RecentBlockhash Hash
+
+	instructions []compiledInstruction
+}
+
+// CompileMessage orders the accounts used by the instructions as required
+// by the Solana runtime (writable signers, read-only signers, writable
+// non-signers then read-only non-signers) with the fee payer first.
+func CompileMessage(feePayer PublicKey, instructions []Instruction, recentBlockhash Hash) (*Message, error) {
+	metas := []AccountMeta{{PublicKey: feePayer, Signer: true, Writable: true}}
+	index := map[PublicKey]int{feePayer: 0}
+
+	add := func(meta AccountMeta) {
+		if i, ok := index[meta.PublicKey]; ok {
+			metas[i].Signer = metas[i].Signer || meta.Signer
+			metas[i].Writable = metas[i].Writable || meta.Writable
+
+			return
+		}
+
+		index[meta.PublicKey] = len(metas)
+		metas = append(metas, meta)
+	}
+
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts {
+			add(account)
+		}
+
+		add(AccountMeta{PublicKey: instruction.ProgramID})
+	}
+
+	msg := &Message{
+		RecentBlockhash: recentBlockhash,
+	}
+
+	for _, class := range []struct{ signer, writable bool }{
+		{true, true},
+		{true, false},
+		{false, true},
+		{false, false},
+	} {
+		for _, meta := range metas {
+			if meta.Signer != class.signer || meta.Writable != class.writable {
+				continue
+			}
+
+			msg.AccountKeys = append(msg.AccountKeys, meta.PublicKey)
+
+			switch {
+			case meta.Signer && meta.Writable:
+				msg.numRequiredSignatures++
+			case meta.Signer:
+				msg.numRequiredSignatures++
+				msg.numReadonlySignedAccounts++
+			case !meta.Writable:
+				msg.numReadonlyUnsignedAccounts++
+			}
+		}
+	}
+
+	if len(msg.AccountKeys) > 256 {
+		return nil, errors.New("too many accounts in transaction")
+	}
+
+	position := make(map[PublicKey]byte, len(msg.AccountKeys))
+	for i, key := range msg.AccountKeys {
+		position[key] = byte(i)
+	}
+
+	for _, instruction := range instructions {
+		compiled := compiledInstruction{
+			programIDIndex: position[instruction.ProgramID],
+			data:           instruction.Data,
+		}
+
+		for _, account := range instruction.Accounts {
+			compiled.accounts = append(compiled.accounts, position[account.PublicKey])
+		}
+
+		msg.instructions = append(msg.instructions, compiled)
+	}
+
+	return msg, nil
+}
+
+// Serialize returns the wire encoding of the message, which is the data
+// signed by each of the transaction's signers.
+func (m *Message) Serialize() []byte {
+	// The high bit of the first byte identifies a versioned message.
+	out := []byte{0x80, m.numRequiredSignatures, m.numReadonlySignedAccounts, m.numReadonlyUnsignedAccounts}
+
+	out = appendCompactU16(out, len(m.AccountKeys))
+	for _, key := range m.AccountKeys {
+		out = append(out, key[:]...)
+	}
+
+	out = append(out, m.RecentBlockhash[:]...)
+
+	out = appendCompactU16(out, len(m.instructions))
+	for _, instruction := range m.instructions {
+		out = append(out, instruction.programIDIndex)
+		out = appendCompactU16(out, len(instruction.accounts))
+		out = append(out, instruction.accounts...)
+		out = appendCompactU16(out, len(instruction.data))
+		out = append(out, instruction.data...)
+	}
+
+	// No address lookup tables are used.
+	return appendCompactU16(out, 0)
+}
+
+// Transaction is a Message along with the signatures of its signers.
+type Transaction struct {
+	Message    *Message
+	Signatures [][ed25519.SignatureSize]byte
+}
+
+// NewTransaction returns an unsigned Transaction for the message.
+func NewTransaction(msg *Message) *Transaction {
+	return &Transaction{
+		Message:    msg,
+		Signatures: make([][ed25519.SignatureSize]byte, msg.numRequiredSignatures),
+	}
+}
+
+// AddSignature adds the signer's signature of the serialized message.
+func (t *Transaction) AddSignature(signer PublicKey, signature []byte) error {
+	if len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature length: %d", len(signature))
+	}
+
+	for i := range t.Signatures {
+		if t.Message.AccountKeys[i] == signer {
+			copy(t.Signatures[i][:], signature)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is not a signer of the transaction", signer)
+}
+
+// Serialize returns the wire encoding of the transaction.  Signatures that
+// haven't been added are encoded as zeros so that the transaction can be
+// completed by the remaining signers (e.g. the fee payer.)
+func (t *Transaction) Serialize() []byte {
+	out := appendCompactU16(nil, len(t.Signatures))
+	for _, sig := range t.Signatures {
+		out = append(out, sig[:]...)
+	}
+
+	return append(out, t.Message.Serialize()...)
+}
+
+// appendCompactU16 appends the Solana "shortvec" encoding of n.
+func appendCompactU16(out []byte, n int) []byte {
+	for {
+		b := byte(n & 0x7f)
+
+		n >>= 7
+		if n == 0 {
+			return append(out, b)
+		}
+
+		out = append(out, b|0x80)
+	}
+}
+
+// SetComputeUnitLimit returns a Compute Budget instruction that limits the
+// compute units that the transaction may consume.
+func SetComputeUnitLimit(units uint32) Instruction {
+	return Instruction{
+		ProgramID: ComputeBudgetProgramID,
+		Data:      binary.LittleEndian.AppendUint32([]byte{2}, units),
+	}
+}
+
+// SetComputeUnitPrice returns a Compute Budget instruction that sets the
+// priority fee paid per compute unit in micro-lamports.
+func SetComputeUnitPrice(microLamports uint64) Instruction {
+	return Instruction{
+		ProgramID: ComputeBudgetProgramID,
+		Data:      binary.LittleEndian.AppendUint64([]byte{3}, microLamports),
+	}
+}
+
+// TransferChecked returns an SPL Token TransferChecked instruction that
+// transfers amount of the mint from the source token account to the
+// destination token account.
+func TransferChecked(tokenProgram, source, mint, destination, owner PublicKey, amount uint64, decimals uint8) Instruction {
+	data := binary.LittleEndian.AppendUint64([]byte{12}, amount)
+
+	return Instruction{
+		ProgramID: tokenProgram,
+		Accounts: []AccountMeta{
+			{PublicKey: source, Writable: true},
+			{PublicKey: mint},
+			{PublicKey: destination, Writable: true},
+			{PublicKey: owner, Signer: true},
+		},
+		Data: append(data, decimals),
+	}
+}
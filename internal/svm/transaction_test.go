@@ -0,0 +1,90 @@
+package svm_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/internal/svm"
+)
+
+func TestBase58(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		data    []byte
+		encoded string
+	}{
+		{name: "text", data: []byte("Hello World!"), encoded: "2NEpo7TZRRrLZSi2U"},
+		{name: "leading zeros", data: []byte{0, 0, 1}, encoded: "112"},
+		{name: "system program", data: make([]byte, 32), encoded: "11111111111111111111111111111111"},
+	} {
+		t.Run("passes - "+test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.encoded, svm.EncodeBase58(test.data))
+
+			data, err := svm.DecodeBase58(test.encoded)
+			require.NoError(t, err)
+			assert.Equal(t, test.data, data)
+		})
+	}
+
+	t.Run("fails - invalid character", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := svm.DecodeBase58("0OIl")
+		require.Error(t, err)
+	})
+}
+
+func TestTransaction(t *testing.T) {
+	t.Parallel()
+
+	feePayer := svm.MustPublicKey("2wmVCSfPxGPjrnMMn7rchp4uaeoTqN39mXFC2zhPdri9")
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	owner := svm.PublicKey(pub)
+	mint := svm.MustPublicKey("4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU")
+
+	source, err := svm.AssociatedTokenAddress(owner, mint, svm.TokenProgramID)
+	require.NoError(t, err)
+
+	destination, err := svm.AssociatedTokenAddress(feePayer, mint, svm.TokenProgramID)
+	require.NoError(t, err)
+	assert.NotEqual(t, source, destination)
+
+	msg, err := svm.CompileMessage(feePayer, []svm.Instruction{
+		svm.SetComputeUnitLimit(20_000),
+		svm.TransferChecked(svm.TokenProgramID, source, mint, destination, owner, 10_000, 6),
+	}, svm.Hash{1})
+	require.NoError(t, err)
+
+	// Signers come first, followed by the writable then read-only accounts.
+	assert.Equal(t, []svm.PublicKey{
+		feePayer,
+		owner,
+		source,
+		destination,
+		svm.ComputeBudgetProgramID,
+		mint,
+		svm.TokenProgramID,
+	}, msg.AccountKeys)
+
+	data := msg.Serialize()
+	assert.Equal(t, []byte{0x80, 2, 1, 3, 7}, data[:5])
+
+	tx := svm.NewTransaction(msg)
+	require.NoError(t, tx.AddSignature(owner, ed25519.Sign(priv, data)))
+	require.Error(t, tx.AddSignature(mint, ed25519.Sign(priv, data)))
+
+	wire := tx.Serialize()
+	require.Len(t, wire, 1+2*ed25519.SignatureSize+len(data))
+	assert.Equal(t, byte(2), wire[0])
+	assert.Equal(t, make([]byte, ed25519.SignatureSize), wire[1:1+ed25519.SignatureSize], "fee payer's signature is left empty")
+	assert.True(t, ed25519.Verify(pub, wire[1+2*ed25519.SignatureSize:], wire[1+ed25519.SignatureSize:1+2*ed25519.SignatureSize]))
+}
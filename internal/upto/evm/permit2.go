@@ -0,0 +1,185 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+var (
+	_ api.PayloadPayer = (*UptoEvm)(nil)
+	_ api.NetworkPayer = (*UptoEvm)(nil)
+	_ api.Validator    = (*UptoEvm)(nil)
+)
+
+// UptoEvm is an api.Payer that handles payment requests on EVM-compatible
+// networks for the "upto" scheme.
+//
+// The payment is a Permit2 PermitTransferFrom signature that allows the
+// spender to transfer any amount up to the seller's MaxAmountRequired.
+// The seller settles the amount actually used, so the buyer must have
+// approved the Permit2 contract to transfer the token.
+//
+// Since the authorization isn't bound to the seller's payTo address, only
+// the payTo address itself or one of the allowed spenders can be named as
+// the spender.
+type UptoEvm struct {
+	signer   api.EVMSigner
+	networks *api.Networks
+	tokens   *exact.Tokens
+	spenders map[common.Address]struct{}
+	options  *api.Options
+	log      *slog.Logger
+}
+
+func NewUptoEvm(signer api.Signer, networks *api.Networks, tokens *exact.Tokens, spenders []common.Address, log *slog.Logger, opts ...api.Option) (*UptoEvm, error) {
+	s, ok := signer.(api.EVMSigner)
+	if !ok {
+		return nil, fmt.Errorf("%w: the Upto EVM scheme requires an EVM signer", api.ErrUnsupportedSigner)
+	}
+
+	options, err := api.NewOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[common.Address]struct{}, len(spenders))
+
+	for _, spender := range spenders {
+		allowed[spender] = struct{}{}
+	}
+
+	return &UptoEvm{
+		signer:   s,
+		networks: networks,
+		tokens:   tokens,
+		spenders: allowed,
+		options:  options,
+		log:      log,
+	}, nil
+}
+
+// Pay implements api.Payer.
+func (u *UptoEvm) Pay(requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	return u.PayContext(context.Background(), requirements)
+}
+
+// PayContext implements api.ContextPayer.
+func (u *UptoEvm) PayContext(ctx context.Context, requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	payment, err := u.PayPayload(ctx, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment.Types()
+}
+
+// PayPayload implements api.PayloadPayer.
+func (u *UptoEvm) PayPayload(ctx context.Context, requirements types.PaymentRequirements) (*api.PaymentPayload, error) {
+	if err := u.Validate(requirements); err != nil {
+		return nil, err
+	}
+
+	switch requirements.Scheme {
+	case "upto":
+		return u.createPaymentUptoEvm(ctx, requirements)
+	default:
+		return nil, fmt.Errorf("unknown payment scheme : %w, %s", http.ErrNotSupported, requirements.Scheme)
+	}
+}
+
+// Supports implements api.NetworkPayer.
+func (u *UptoEvm) Supports(network string) bool {
+	_, err := u.networks.Lookup(network)
+
+	return err == nil
+}
+
+// Validate implements api.Validator.
+func (u *UptoEvm) Validate(requirements types.PaymentRequirements) error {
+	var verr api.ValidationError
+
+	extra := exact.ValidateRequirements(&verr, u.networks, requirements)
+
+	if requirements.Scheme != "" && requirements.Scheme != string(api.SchemeUpto) {
+		verr.Add("scheme", "%q is not supported", requirements.Scheme)
+	}
+
+	if spender, ok := extra["spender"].(string); ok && common.IsHexAddress(spender) && !u.allowed(common.HexToAddress(spender), requirements.PayTo) {
+		verr.Add("extra.spender", "%q is neither the payTo address nor an allowed spender", spender)
+	}
+
+	return verr.Err()
+}
+
+// allowed returns true if the spender can be authorized to transfer the
+// buyer's tokens.
+func (u *UptoEvm) allowed(spender common.Address, payTo string) bool {
+	if common.IsHexAddress(payTo) && spender == common.HexToAddress(payTo) {
+		return true
+	}
+
+	_, ok := u.spenders[spender]
+
+	return ok
+}
+
+// Scheme implements api.Payer.
+func (u *UptoEvm) Scheme() api.Scheme {
+	return api.SchemeUpto
+}
+
+func (u *UptoEvm) createPaymentUptoEvm(ctx context.Context, requirements types.PaymentRequirements) (*api.PaymentPayload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	extra, err := exact.ParseExtra(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := u.networks.Lookup(requirements.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := u.tokens.Lookup(network.ChainID, common.HexToAddress(requirements.Asset))
+	if err != nil {
+		return nil, err
+	}
+
+	authorization, signature, err := exact.SignPermit2(ctx, u.signer, u.options, network.ChainID, token, extra.SpenderOr(requirements.PayTo), requirements, u.log)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &api.UptoEvmPayload{
+		Authorization: authorization,
+		Signature:     signature,
+	}
+
+	u.log.Info(
+		"x402 payment authorized",
+		slog.String("from", authorization.From),
+		slog.String("spender", authorization.Spender),
+		slog.String("max", authorization.Permitted.Amount),
+		slog.String("scheme", requirements.Scheme),
+		slog.String("network", requirements.Network),
+		slog.String("name", token.Name),
+	)
+
+	return &api.PaymentPayload{
+		X402Version: 1,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload:     payload,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package evm_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gotest.tools/v3/golden"
+
+	"github.com/selesy/x402-buyer/internal/eip712"
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/internal/upto/evm"
+	"github.com/selesy/x402-buyer/pkg/api"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestUptoEvm(t *testing.T) {
+	t.Parallel()
+
+	paymentRequestJSON := golden.Get(t, "upto_payment_request.json")
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	var paymentRequest api.PaymentRequest
+
+	require.NoError(t, json.Unmarshal(paymentRequestJSON, &paymentRequest))
+	require.Len(t, paymentRequest.Accepts, 1)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	facilitator := common.HexToAddress("0x60ac86571E55F9735F00cE9e28361d203977B260")
+
+	payer, err := evm.NewUptoEvm(signer, api.NewNetworks(), exact.NewTokens(), []common.Address{facilitator}, log, api.WithNowFunc(fixedNowFunc(t)), api.WithNonceFunc(fixedNonceFunc(t)))
+	require.NoError(t, err)
+
+	t.Run("passes", func(t *testing.T) {
+		t.Parallel()
+
+		paymentPayload, err := payer.PayPayload(t.Context(), paymentRequest.Accepts[0])
+		require.NoError(t, err)
+
+		data, err := json.Marshal(paymentPayload)
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, json.Indent(buf, data, "", "  "))
+
+		golden.Assert(t, buf.String()+"\n", "upto_payment_payload.golden")
+
+		payload, ok := paymentPayload.UptoEvm()
+		require.True(t, ok)
+		assert.Equal(t, "250000", payload.Authorization.Permitted.Amount)
+		assert.Equal(t, "0x60ac86571E55F9735F00cE9e28361d203977B260", payload.Authorization.Spender)
+
+		// The signature must recover to the buyer's address.
+		hash, _, err := apitypes.TypedDataAndHash(eip712.PermitTransferFrom(big.NewInt(84532), payload.Authorization))
+		require.NoError(t, err)
+
+		sig, err := hexutil.Decode(payload.Signature)
+		require.NoError(t, err)
+
+		sig[64] -= 27
+
+		pubKey, err := crypto.SigToPub(hash, sig)
+		require.NoError(t, err)
+		assert.Equal(t, signer.Address(), crypto.PubkeyToAddress(*pubKey))
+	})
+
+	t.Run("passes - spender defaults to payTo", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Extra = nil
+
+		paymentPayload, err := payer.PayPayload(t.Context(), requirements)
+		require.NoError(t, err)
+
+		payload, ok := paymentPayload.UptoEvm()
+		require.True(t, ok)
+		assert.Equal(t, requirements.PayTo, payload.Authorization.Spender)
+	})
+
+	t.Run("fails - types.PaymentPayload", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := payer.Pay(paymentRequest.Accepts[0])
+		require.ErrorIs(t, err, api.ErrUnsupportedPayload)
+	})
+
+	t.Run("fails - invalid spender", func(t *testing.T) {
+		t.Parallel()
+
+		extra := json.RawMessage(`{"spender":"facilitator"}`)
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Extra = &extra
+
+		_, err := payer.Pay(requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - spender not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		extra := json.RawMessage(`{"spender":"0x5FbDB2315678afecb367f032d93F642f64180aa3"}`)
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Extra = &extra
+
+		_, err := payer.PayPayload(t.Context(), requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - exact scheme", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Scheme = string(api.SchemeExact)
+
+		_, err := payer.Pay(requirements)
+		require.ErrorIs(t, err, api.ErrInvalidRequirements)
+	})
+
+	t.Run("fails - unknown token", func(t *testing.T) {
+		t.Parallel()
+
+		requirements := paymentRequest.Accepts[0]
+		requirements.Asset = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+
+		_, err := payer.Pay(requirements)
+		require.ErrorIs(t, err, exact.ErrUnknownToken)
+	})
+}
+
+func fixedNonceFunc(t *testing.T) api.NonceFunc {
+	t.Helper()
+
+	nonce, err := hex.DecodeString("140fd607c52d266941aa8d8241891654b6d7ab50a02028cb900c746e3a1bf4dd")
+	require.NoError(t, err)
+
+	return func() []byte {
+		return nonce
+	}
+}
+
+func fixedNowFunc(t *testing.T) api.NowFunc {
+	t.Helper()
+
+	now, err := time.Parse(time.RFC3339, "2001-02-03T04:05:06Z")
+	require.NoError(t, err)
+
+	return func() time.Time {
+		return now
+	}
+}
@@ -0,0 +1,90 @@
+package buyer_test
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestNonceSeed(t *testing.T) {
+	t.Parallel()
+
+	const payReq = `{"accepts":[{"scheme":"exact","network":"simulated","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x5FbDB2315678afecb367f032d93F642f64180aa3"}],"error":"X-PAYMENT header is required","x402Version":1}`
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	// pay returns the nonce of a payment made by a new Transport.
+	pay := func(t *testing.T, opts ...buyer.Option) string {
+		t.Helper()
+
+		next := newMockTransport(t, &http.Response{
+			StatusCode: http.StatusPaymentRequired,
+			Body:       io.NopCloser(strings.NewReader(payReq)),
+		}, &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("Response body")),
+		})
+
+		opts = append(opts,
+			buyer.WithNetwork("simulated", 1337),
+			buyer.WithToken(simulatedToken),
+			buyer.WithNonceSeed([]byte("correct horse battery staple")),
+		)
+
+		trans, err := buyer.NewTransport(next, signer, opts...)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
+		require.NoError(t, err)
+
+		resp, err := trans.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		payment, ok := buyer.PaymentFromResponse(resp)
+		require.True(t, ok)
+
+		payload, ok := payment.Payload.ExactEvm()
+		require.True(t, ok)
+
+		return payload.Authorization.Nonce
+	}
+
+	t.Run("passes - nonces aren't reused after a restart", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nonces.json")
+
+		first := pay(t, buyer.WithNonceStore(path))
+		second := pay(t, buyer.WithNonceStore(path))
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("passes - nonces used on-chain are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		first := pay(t, buyer.WithNonceStore(filepath.Join(t.TempDir(), "nonces.json")))
+
+		// A new store would derive the same nonce but the token reports
+		// that it has already been used.
+		backend := newSimulatedBackend(t, signer.Address(), 10000, 0, 0, common.BytesToHash(hexutil.MustDecode(first)))
+
+		second := pay(t,
+			buyer.WithNonceStore(filepath.Join(t.TempDir(), "nonces.json")),
+			buyer.WithContractCaller("simulated", backend),
+		)
+		assert.NotEqual(t, first, second)
+	})
+}
@@ -2,15 +2,49 @@ package buyer
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
+	"math/big"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/selesy/x402-buyer/internal/exact"
+	"github.com/selesy/x402-buyer/internal/exact/svm"
+	"github.com/selesy/x402-buyer/internal/nonce"
 	"github.com/selesy/x402-buyer/internal/observability"
+	solana "github.com/selesy/x402-buyer/internal/svm"
+	"github.com/selesy/x402-buyer/pkg/api"
 )
 
 type config struct {
-	client *http.Client
-	log    *slog.Logger
+	client   *http.Client
+	log      *slog.Logger
+	selector Selector
+	payers   *registry
+	networks *api.Networks
+	tokens   *exact.Tokens
+	budget   *budget
+	approver Approver
+
+	fallbackReasons map[string]struct{}
+	cache           *requirementsCache
+	skew            *skew
+	payerOpts       []api.Option
+	solanaRPC       map[string]string
+	mints           *svm.Mints
+	callers         map[string]ethereum.ContractCaller
+	rpcClients      []*ethclient.Client
+	nonceStore      nonce.Store
+	nonceSeed       []byte
+	upto            []common.Address
+
+	spoolThreshold    int64
+	maxReplayableBody int64
 }
 
 // Option represents a means of altering the default configuration of the
@@ -24,20 +58,55 @@ func newConfig(opts ...Option) (*config, error) {
 		client: &http.Client{
 			Transport: http.DefaultTransport,
 		},
-		log: slog.New(observability.NewNoopHandler()),
+		log:      slog.New(observability.NewNoopHandler()),
+		selector: SelectFirst(),
+		networks: api.NewNetworks(),
+		tokens:   exact.NewTokens(),
+		budget:   newBudget(),
+
+		solanaRPC:      map[string]string{},
+		mints:          svm.NewMints(),
+		callers:        map[string]ethereum.ContractCaller{},
+		spoolThreshold: defaultSpoolThreshold,
 	}
 
+	cfg.payers = newRegistry(cfg.networks)
+
 	for _, opt := range opts {
 		errs = errors.Join(errs, opt(cfg))
 	}
 
+	// Derived nonces are repeated after a restart unless the counters are
+	// persisted.
+	if cfg.nonceSeed != nil && cfg.nonceStore == nil {
+		errs = errors.Join(errs, WithNonceStore("")(cfg))
+	}
+
+	// Networks might be registered after their RPC endpoints.
+	for name := range cfg.callers {
+		if _, err := cfg.networks.Lookup(name); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+
 	if errs != nil {
+		cfg.close()
+
 		return nil, errs
 	}
 
 	return cfg, nil
 }
 
+// close closes the JSON-RPC clients created by WithRPC.
+func (c *config) close() {
+	for _, client := range c.rpcClients {
+		client.Close()
+	}
+
+	c.rpcClients = nil
+}
+
 // WithClient is an Option that allows the user to provide a custom http.Client
 // whose http.RoundTripper will be wrapped to allow x402 payments.
 //
@@ -65,3 +134,420 @@ func WithLogger(log *slog.Logger) Option {
 		return nil
 	}
 }
+
+// WithSelector is an Option that allows the user to provide a Selector that
+// chooses which of the payment requirements offered by a seller is paid.
+//
+// If not provided, SelectFirst is used and the first requirement that the
+// buyer is able to pay is chosen.
+func WithSelector(selector Selector) Option {
+	return func(c *config) error {
+		if selector == nil {
+			return errors.New("selector is required")
+		}
+
+		c.selector = selector
+
+		return nil
+	}
+}
+
+// WithPayer is an Option that registers an api.Payer that will make payments
+// for its api.Scheme on each of the provided networks.
+//
+// If no networks are provided, the api.Payer is used for any network within
+// its api.Scheme that isn't handled by a payer registered for that specific
+// network.  Payers that also implement api.NetworkPayer are only used for the
+// networks they support.  Payers registered using this Option take precedence
+// over the built-in payers, which are registered for the "exact" scheme on
+// EVM networks when the buyer's api.Signer is an api.EVMSigner.
+//
+// Networks can be identified by either their name or CAIP-2 identifier and
+// the api.Payer is used for requirements that identify the network either
+// way.  Networks registered using WithNetwork must be provided before this
+// Option for their names to be matched.
+func WithPayer(payer api.Payer, networks ...string) Option {
+	return func(c *config) error {
+		if payer == nil {
+			return errors.New("payer is required")
+		}
+
+		c.payers.register(payer, networks...)
+
+		return nil
+	}
+}
+
+// WithUpto is an Option that registers the built-in payer for the "upto"
+// scheme on EVM networks.  An "upto" payment authorizes its spender to
+// transfer any amount up to the seller's maximum using Permit2, so the
+// payer only authorizes the seller's payTo address or one of the provided
+// spenders (e.g. a trusted facilitator) to make the transfer.
+//
+// The payer is only registered when the buyer's api.Signer is an
+// api.EVMSigner.
+func WithUpto(spenders ...common.Address) Option {
+	return func(c *config) error {
+		c.upto = append([]common.Address{}, spenders...)
+
+		return nil
+	}
+}
+
+// WithNetwork is an Option that registers an EVM-compatible network that
+// the buyer can make payments on.  This allows payments on private or
+// recently launched networks that are not among the api.KnownNetworks.
+//
+// Networks identified in payment requirements by their CAIP-2 identifier
+// (eip155:<chain ID>) don't need to be registered.
+func WithNetwork(name string, chainID int64) Option {
+	return func(c *config) error {
+		return c.networks.Register(api.Network{
+			Name:    name,
+			ChainID: big.NewInt(chainID),
+		})
+	}
+}
+
+// WithToken is an Option that registers an ERC-20 token that the buyer can
+// make payments with on EVM-compatible networks.  Payment requirements are
+// only paid if their asset is a registered token and the EIP-712 domain
+// name and version provided by the seller (if any) match those of the
+// token.  The well-known USDC deployments are registered by default.
+func WithToken(token api.Token) Option {
+	return func(c *config) error {
+		if token.Address == (common.Address{}) {
+			return errors.New("token address is required")
+		}
+
+		return c.tokens.Register(token)
+	}
+}
+
+// WithRPC is an Option that sets the JSON-RPC endpoint of an EVM-compatible
+// network (identified by its name or CAIP-2 identifier.)  When an endpoint
+// is available, the buyer's token balance (and Permit2 allowance when
+// needed) is checked before each payment on the network is signed and
+// payments that can't be covered fail with ErrInsufficientFunds.  The
+// endpoint is also used to read the nonces needed for EIP-2612 permits.
+//
+// The connection to the endpoint is owned by the Transport and is closed
+// by Transport.Close.  The http.Clients returned by the ClientFor
+// functions use a *Transport so it can be closed using:
+//
+//	client.Transport.(*buyer.Transport).Close()
+func WithRPC(network, endpoint string) Option {
+	return func(c *config) error {
+		client, err := ethclient.Dial(endpoint)
+		if err != nil {
+			return err
+		}
+
+		c.rpcClients = append(c.rpcClients, client)
+
+		return WithContractCaller(network, client)(c)
+	}
+}
+
+// WithContractCaller is like WithRPC except that the network's contracts
+// are called using the provided ethereum.ContractCaller (e.g. an existing
+// *ethclient.Client.)  The caller isn't closed by Transport.Close so its
+// lifetime can be managed by the application.
+func WithContractCaller(network string, caller ethereum.ContractCaller) Option {
+	return func(c *config) error {
+		if caller == nil {
+			return errors.New("contract caller is required")
+		}
+
+		c.callers[network] = caller
+
+		return nil
+	}
+}
+
+// WithSolanaRPC is an Option that sets the JSON-RPC endpoint used to
+// create payments on a Solana network (identified by its name or CAIP-2
+// identifier.)  Payments on Solana require the latest block hash, so the
+// public endpoints are used by default but are heavily rate-limited.
+// Requests to the endpoint are made using the wrapped http.RoundTripper
+// and the timeout of the client provided using WithClient.
+func WithSolanaRPC(network, endpoint string) Option {
+	return func(c *config) error {
+		known, ok := svm.LookupNetwork(network)
+		if !ok {
+			return fmt.Errorf("%w: %s", api.ErrUnknownNetwork, network)
+		}
+
+		c.solanaRPC[known.Name] = endpoint
+
+		return nil
+	}
+}
+
+// WithSolanaMint is an Option that registers an SPL token mint that the
+// buyer can make payments with on a Solana network (identified by its name
+// or CAIP-2 identifier.)  Payment requirements on Solana are only paid if
+// their asset is a registered mint.  The USDC mints are registered by
+// default.
+func WithSolanaMint(network, mint string) Option {
+	return func(c *config) error {
+		address, err := solana.ParsePublicKey(mint)
+		if err != nil {
+			return err
+		}
+
+		return c.mints.Register(svm.Mint{
+			Network: network,
+			Address: address,
+		})
+	}
+}
+
+// WithNonceStore is an Option that records the counters used to derive the
+// nonces of ERC-3009 and Permit2 authorizations (see WithNonceSeed) in a
+// file at path so that they're never reused, even after a restart.  Random
+// nonces are 256 bits so they aren't recorded.  If path is empty,
+// nonces.json in the user's x402-buyer configuration directory is used.
+// The file is locked while its counters are updated so it can be shared by
+// the Transports of a process and by multiple processes.
+//
+// When a network's JSON-RPC endpoint is provided using WithRPC, each
+// ERC-3009 nonce is also checked using the token's authorizationState
+// method before it's used.
+func WithNonceStore(path string) Option {
+	return func(c *config) error {
+		if path == "" {
+			var err error
+
+			if path, err = nonce.DefaultFileStorePath(); err != nil {
+				return err
+			}
+		}
+
+		store, err := nonce.NewFileStore(path)
+		if err != nil {
+			return err
+		}
+
+		c.nonceStore = store
+
+		return nil
+	}
+}
+
+// WithNonceSeed is an Option that derives the nonces used by ERC-3009 and
+// Permit2 authorizations from the seed and a counter rather than
+// generating them randomly.  The counters are persisted using the store
+// provided by WithNonceStore (or the default file if none is provided.)
+func WithNonceSeed(seed []byte) Option {
+	return func(c *config) error {
+		if len(seed) == 0 {
+			return errors.New("nonce seed must not be empty")
+		}
+
+		c.nonceSeed = append([]byte{}, seed...)
+
+		return nil
+	}
+}
+
+// WithPayerOptions is an Option that configures the payments created by the
+// built-in payers.  For instance, api.WithNonceFunc can be used to control
+// the nonces used by authorizations.  The offset estimated by
+// WithClockSkewCompensation is added to the time returned by an
+// api.WithNowFunc provided using this Option.
+func WithPayerOptions(opts ...api.Option) Option {
+	return func(c *config) error {
+		if _, err := api.NewOptions(opts...); err != nil {
+			return err
+		}
+
+		c.payerOpts = append(c.payerOpts, opts...)
+
+		return nil
+	}
+}
+
+// WithValidityWindow is an Option that sets how far before the current time
+// a payment's authorization becomes valid and the longest time that it
+// remains valid.  A validFor of zero allows the authorization to remain
+// valid for the timeout requested by the seller.
+//
+// If not provided, authorizations become valid api.DefaultValidAfter before
+// they're created and remain valid for the seller's timeout.
+func WithValidityWindow(validAfter, validFor time.Duration) Option {
+	return WithPayerOptions(api.WithValidityWindow(validAfter, validFor))
+}
+
+// WithClockSkewCompensation is an Option that adjusts the time used to
+// create payment authorizations by the difference between the local clock
+// and the Date header of the seller's 402 Payment Required response.  This
+// prevents hosts with drifting clocks from creating authorizations that are
+// rejected as expired or not yet valid.  The difference is tracked for each
+// seller's host and differences of more than five minutes are ignored.
+func WithClockSkewCompensation() Option {
+	return func(c *config) error {
+		c.skew = newSkew()
+
+		return nil
+	}
+}
+
+// WithApprover is an Option that allows the user to provide an Approver
+// that is consulted before each payment is signed.
+//
+// If not provided, every payment that's within the configured budgets is
+// made automatically.
+func WithApprover(approver Approver) Option {
+	return func(c *config) error {
+		c.approver = approver
+
+		return nil
+	}
+}
+
+// WithBodySpoolThreshold is an Option that sets the size (in bytes) above
+// which request bodies are written to a temporary file rather than held in
+// memory so that they can be replayed if a payment is required.
+//
+// Bodies of requests that provide an http.Request.GetBody function (as
+// those created by http.NewRequest with a *bytes.Buffer, *bytes.Reader or
+// *strings.Reader do) are never buffered.  If not provided, the threshold is
+// 1 MiB.
+func WithBodySpoolThreshold(size int64) Option {
+	return func(c *config) error {
+		if size < 0 {
+			return errors.New("body spool threshold must not be negative")
+		}
+
+		c.spoolThreshold = size
+
+		return nil
+	}
+}
+
+// WithMaxReplayableBodySize is an Option that sets the maximum size (in
+// bytes) of a request body that will be buffered so that it can be
+// replayed if a payment is required.  Larger bodies are streamed and, if
+// a payment is then required, ErrBodyNotReplayable is returned.
+//
+// If not provided, or if the size is zero, bodies of any size are
+// replayable.
+func WithMaxReplayableBodySize(size int64) Option {
+	return func(c *config) error {
+		if size < 0 {
+			return errors.New("maximum replayable body size must not be negative")
+		}
+
+		c.maxReplayableBody = size
+
+		return nil
+	}
+}
+
+// WithFallback is an Option that allows the buyer to retry a rejected
+// payment using the next of the seller's acceptable payment requirements
+// with a different network or asset.  A fallback payment is only attempted
+// when the seller's reason for rejecting the payment is one of the provided
+// reasons.
+//
+// If no reasons are provided, DefaultFallbackReasons are used.  If this
+// Option is not provided, a rejected payment results in a
+// PaymentRejectedError.
+func WithFallback(reasons ...string) Option {
+	return func(c *config) error {
+		if len(reasons) == 0 {
+			reasons = DefaultFallbackReasons
+		}
+
+		c.fallbackReasons = map[string]struct{}{}
+
+		for _, reason := range reasons {
+			c.fallbackReasons[reason] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+// WithRequirementsCache is an Option that remembers the payment
+// requirements that were paid for each resource (identified by the
+// request's method and URL) for the provided time-to-live.  Subsequent
+// requests for the resource include a payment on the first attempt,
+// saving a round-trip.
+//
+// If the seller responds to such a request with a 402 Payment Required,
+// the cached requirements are discarded and the payment is made using the
+// requirements from the new response.  If not provided, the buyer always
+// waits for a 402 Payment Required response before paying.
+func WithRequirementsCache(ttl time.Duration) Option {
+	return func(c *config) error {
+		if ttl <= 0 {
+			return errors.New("requirements cache time-to-live must be positive")
+		}
+
+		c.cache = newRequirementsCache(ttl)
+
+		return nil
+	}
+}
+
+// WithMaxPayment is an Option that limits the amount of any single payment
+// to max, expressed in the atomic units of the asset being paid.
+func WithMaxPayment(max *big.Int) Option {
+	return func(c *config) error {
+		if max == nil || max.Sign() < 0 {
+			return errors.New("maximum payment must not be negative")
+		}
+
+		c.budget.maxPayment = new(big.Int).Set(max)
+
+		return nil
+	}
+}
+
+// WithBudget is an Option that limits the total amount of all payments made
+// within the rolling window to max.  If window is zero, the limit applies
+// for the lifetime of the http.RoundTripper.
+//
+// Amounts are expressed in atomic units and are summed without regard to
+// the asset being paid, so this Option is most useful when all payments are
+// made with assets sharing the same number of decimals.  See WithAssetBudget
+// to limit payments for a specific asset.
+func WithBudget(max *big.Int, window time.Duration) Option {
+	return budgetOption("total", max, window, func(string, string) bool {
+		return true
+	})
+}
+
+// WithHostBudget is like WithBudget except that it only limits payments made
+// for requests to the named host.
+func WithHostBudget(host string, max *big.Int, window time.Duration) Option {
+	return budgetOption("host "+host, max, window, func(h, _ string) bool {
+		return strings.EqualFold(h, host)
+	})
+}
+
+// WithAssetBudget is like WithBudget except that it only limits payments
+// made using the asset at the provided (contract) address.
+func WithAssetBudget(asset string, max *big.Int, window time.Duration) Option {
+	return budgetOption("asset "+asset, max, window, func(_, a string) bool {
+		return strings.EqualFold(a, asset)
+	})
+}
+
+func budgetOption(desc string, max *big.Int, window time.Duration, matches func(host, asset string) bool) Option {
+	return func(c *config) error {
+		if max == nil || max.Sign() < 0 {
+			return fmt.Errorf("%s budget must not be negative", desc)
+		}
+
+		if window < 0 {
+			return fmt.Errorf("%s budget window must not be negative", desc)
+		}
+
+		c.budget.addLimit(desc, new(big.Int).Set(max), window, matches)
+
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package apitest
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/require"
+)
+
+// ExternalSigner is a stand-in for a Clef-compatible external signer that
+// signs using the PrivateKey.  It records the typed data it's asked to
+// sign.
+type ExternalSigner struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	typedData []apitypes.TypedData
+}
+
+// NewExternalSigner starts an ExternalSigner that serves the Clef JSON-RPC
+// API over HTTP.  The server is closed when the test completes.
+func NewExternalSigner(t *testing.T) *ExternalSigner {
+	t.Helper()
+
+	signer := &ExternalSigner{}
+
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("account", &externalSignerAPI{t: t, signer: signer}))
+
+	signer.Server = httptest.NewServer(srv)
+
+	t.Cleanup(func() {
+		signer.Close()
+		srv.Stop()
+	})
+
+	return signer
+}
+
+// TypedData returns the typed data that the ExternalSigner has signed.
+func (s *ExternalSigner) TypedData() []apitypes.TypedData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]apitypes.TypedData{}, s.typedData...)
+}
+
+type externalSignerAPI struct {
+	t      *testing.T
+	signer *ExternalSigner
+}
+
+// List implements account_list.
+func (a *externalSignerAPI) List(_ context.Context) ([]common.Address, error) {
+	return []common.Address{crypto.PubkeyToAddress(PrivateKey(a.t).PublicKey)}, nil
+}
+
+// SignTypedData implements account_signTypedData.
+func (a *externalSignerAPI) SignTypedData(_ context.Context, addr common.MixedcaseAddress, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	priv := PrivateKey(a.t)
+
+	if addr.Address() != crypto.PubkeyToAddress(priv.PublicKey) {
+		return nil, errors.New("request denied")
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := crypto.Sign(hash, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	a.signer.mu.Lock()
+	a.signer.typedData = append(a.signer.typedData, typedData)
+	a.signer.mu.Unlock()
+
+	sig[64] += 27
+
+	return sig, nil
+}
@@ -24,6 +24,8 @@ const (
 	NotOnCurvePrivateKeyHex      = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
 
 	Passphrase = "LetMeIn"
+
+	Ed25519SeedHex = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
 )
 
 func TestSigner(t *testing.T, signer api.Signer) {
@@ -50,6 +52,16 @@ func TestDataSigner(t *testing.T, signer api.Signer) {
 	assert.Equal(t, expSig, hex.EncodeToString(actSig))
 }
 
+// TestTypedDataSigner checks the signer's signature of the typed data
+// returned by TransferWithAuthorizationTypedData.
+func TestTypedDataSigner(t *testing.T, signer api.TypedDataSigner) {
+	const expSig = "4134c5a9c223b337acaa8085bb4553787fa159a809793f6920044766d55271b77eb01e102b9525edffcac69c31a4c1d51c7fee78bab28bd716f3bb5181ed31001b"
+
+	actSig, err := signer.SignTypedData(t.Context(), TransferWithAuthorizationTypedData(t))
+	require.NoError(t, err)
+	assert.Equal(t, expSig, hex.EncodeToString(actSig))
+}
+
 func PrivateKey(t *testing.T) *ecdsa.PrivateKey {
 	t.Helper()
 
@@ -101,7 +113,27 @@ func TransferWithAuthorizationHash(t *testing.T) ([]byte, string) {
 		expData = "190102fa7265e7c5d81118673727957699e4d68f74cd74b7db77da710fe8a2c7834f4ef85a66e9f161738930fbdba8ae123e7abd7bd10ce397381f794ad74073192f"
 	)
 
-	typedData := apitypes.TypedData{
+	hash, data, err := apitypes.TypedDataAndHash(TransferWithAuthorizationTypedData(t))
+	require.NoError(t, err)
+	require.Equal(t, expHash, hex.EncodeToString(hash))
+	require.Equal(t, expData, hex.EncodeToString([]byte(data)))
+
+	hash2 := crypto.Keccak256Hash([]byte(data))
+	require.Equal(t, expHash, hex.EncodeToString(hash2.Bytes()))
+
+	t.Log("hash:", hex.EncodeToString(hash))
+	t.Log("hash2:", hex.EncodeToString(hash2.Bytes()))
+
+	return hash, data
+}
+
+// TransferWithAuthorizationTypedData returns the typed data of an ERC-3009
+// TransferWithAuthorization whose hash is returned by
+// TransferWithAuthorizationHash.
+func TransferWithAuthorizationTypedData(t *testing.T) apitypes.TypedData {
+	t.Helper()
+
+	return apitypes.TypedData{
 		Types: apitypes.Types{
 			"TransferWithAuthorization": []apitypes.Type{
 				{Name: "from", Type: "address"},
@@ -135,17 +167,4 @@ func TransferWithAuthorizationHash(t *testing.T) ([]byte, string) {
 			"nonce":       "0xd8ac8930d08bfa8ff03af000ef78f0c624f30047d52e62b3ae8e3b9e2b6462ca",
 		},
 	}
-
-	hash, data, err := apitypes.TypedDataAndHash(typedData)
-	require.NoError(t, err)
-	require.Equal(t, expHash, hex.EncodeToString(hash))
-	require.Equal(t, expData, hex.EncodeToString([]byte(data)))
-
-	hash2 := crypto.Keccak256Hash([]byte(data))
-	require.Equal(t, expHash, hex.EncodeToString(hash2.Bytes()))
-
-	t.Log("hash:", hex.EncodeToString(hash))
-	t.Log("hash2:", hex.EncodeToString(hash2.Bytes()))
-
-	return hash, data
 }
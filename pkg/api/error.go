@@ -7,6 +7,17 @@ import (
 
 var ErrFailedPayloadCreate = errors.New("failed to create PaymentPayload")
 
+// ErrUnsupportedSigner is returned by a payer's constructor when the
+// api.Signer can't be used to make its payments (e.g. an api.SVMSigner is
+// provided to an EVM payer.)
+var ErrUnsupportedSigner = errors.New("unsupported signer")
+
+// ErrUnsupportedPayload is returned when a payment's payload can't be
+// represented by a types.PaymentPayload, which only holds ERC-3009
+// authorizations.  Use a PayloadPayer's PayPayload method to create such
+// payments.
+var ErrUnsupportedPayload = errors.New("payload not supported by types.PaymentPayload")
+
 func FailedPaymentPayloadCreation(err error) error {
 	return fmt.Errorf("%w: %w", ErrFailedPayloadCreate, err)
 }
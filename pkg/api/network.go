@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrUnknownNetwork is returned when a network is not present in the
+// Networks registry.
+var ErrUnknownNetwork = errors.New("unknown network")
+
+// caip2Prefix is the CAIP-2 namespace of EVM-compatible chains.
+const caip2Prefix = "eip155:"
+
+// Network describes an EVM-compatible network on which payments can be
+// made.
+type Network struct {
+	// Name is the name used to identify the network in version 1 of the
+	// x402 protocol (e.g. base-sepolia.)
+	Name string
+	// ChainID is the EIP-155 chain ID of the network.
+	ChainID *big.Int
+}
+
+// CAIP2 returns the CAIP-2 identifier (e.g. eip155:84532) used to identify
+// the network in version 2 of the x402 protocol.
+func (n Network) CAIP2() string {
+	return caip2Prefix + n.ChainID.String()
+}
+
+// KnownNetworks are the EVM-compatible networks that x402 sellers accept
+// payments on.
+var KnownNetworks = []Network{
+	{Name: "base", ChainID: big.NewInt(8453)},
+	{Name: "base-sepolia", ChainID: big.NewInt(84532)},
+	{Name: "avalanche", ChainID: big.NewInt(43114)},
+	{Name: "avalanche-fuji", ChainID: big.NewInt(43113)},
+	{Name: "polygon", ChainID: big.NewInt(137)},
+	{Name: "polygon-amoy", ChainID: big.NewInt(80002)},
+	{Name: "sei", ChainID: big.NewInt(1329)},
+	{Name: "sei-testnet", ChainID: big.NewInt(1328)},
+	{Name: "iotex", ChainID: big.NewInt(4689)},
+	{Name: "peaq", ChainID: big.NewInt(3338)},
+}
+
+// Networks is a registry of EVM-compatible networks that resolves the
+// network named in a seller's payment requirements to its chain ID.  A
+// Networks registry is safe for concurrent use.
+type Networks struct {
+	mu        sync.RWMutex
+	byName    map[string]Network
+	byChainID map[string]Network
+}
+
+// NewNetworks returns a Networks registry containing the KnownNetworks.
+func NewNetworks() *Networks {
+	n := &Networks{
+		byName:    map[string]Network{},
+		byChainID: map[string]Network{},
+	}
+
+	for _, network := range KnownNetworks {
+		_ = n.Register(network)
+	}
+
+	return n
+}
+
+// Register adds a network to the registry, replacing any network with the
+// same name or chain ID.
+func (n *Networks) Register(network Network) error {
+	if network.Name == "" {
+		return errors.New("network name is required")
+	}
+
+	if network.ChainID == nil || network.ChainID.Sign() <= 0 {
+		return fmt.Errorf("network %s requires a positive chain ID", network.Name)
+	}
+
+	network.ChainID = new(big.Int).Set(network.ChainID)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.byName[network.Name] = network
+	n.byChainID[network.ChainID.String()] = network
+
+	return nil
+}
+
+// Lookup returns the Network identified either by its name or by its
+// CAIP-2 identifier.  Since CAIP-2 identifiers include the chain ID, any
+// well-formed eip155:<chain ID> identifier can be resolved whether or not
+// the network has been registered.
+func (n *Networks) Lookup(network string) (Network, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if known, ok := n.byName[network]; ok {
+		return known, nil
+	}
+
+	id, ok := strings.CutPrefix(network, caip2Prefix)
+	if !ok {
+		return Network{}, fmt.Errorf("%w: %s", ErrUnknownNetwork, network)
+	}
+
+	chainID, ok := new(big.Int).SetString(id, 10)
+	if !ok || chainID.Sign() <= 0 {
+		return Network{}, fmt.Errorf("%w: %s", ErrUnknownNetwork, network)
+	}
+
+	if known, ok := n.byChainID[chainID.String()]; ok {
+		return known, nil
+	}
+
+	return Network{Name: network, ChainID: chainID}, nil
+}
+
+// Token describes an ERC-20 token on an EVM-compatible network along with
+// the EIP-712 domain used to sign authorizations to transfer it.
+type Token struct {
+	// ChainID is the EIP-155 chain ID of the network the token is on.
+	ChainID *big.Int
+	// Address is the address of the token's contract.
+	Address common.Address
+	// Name is the EIP-712 domain name which must exactly match the value
+	// returned by the contract's name() method.
+	Name string
+	// Version is the EIP-712 domain version of the contract.
+	Version string
+	// Decimals is the number of decimals used by the token.
+	Decimals uint8
+}
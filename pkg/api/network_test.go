@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+func TestNetworks(t *testing.T) {
+	t.Parallel()
+
+	networks := api.NewNetworks()
+	require.NoError(t, networks.Register(api.Network{Name: "private-l2", ChainID: big.NewInt(31337)}))
+
+	for _, tc := range []struct {
+		network string
+		name    string
+		chainID int64
+	}{
+		{network: "base", name: "base", chainID: 8453},
+		{network: "eip155:84532", name: "base-sepolia", chainID: 84532},
+		{network: "avalanche-fuji", name: "avalanche-fuji", chainID: 43113},
+		{network: "polygon-amoy", name: "polygon-amoy", chainID: 80002},
+		{network: "private-l2", name: "private-l2", chainID: 31337},
+		{network: "eip155:31337", name: "private-l2", chainID: 31337},
+		{network: "eip155:10", name: "eip155:10", chainID: 10},
+	} {
+		t.Run("passes - "+tc.network, func(t *testing.T) {
+			t.Parallel()
+
+			network, err := networks.Lookup(tc.network)
+			require.NoError(t, err)
+			assert.Equal(t, tc.name, network.Name)
+			assert.Equal(t, big.NewInt(tc.chainID), network.ChainID)
+			assert.Equal(t, "eip155:"+network.ChainID.String(), network.CAIP2())
+		})
+	}
+
+	for _, network := range []string{"solana", "eip155:", "eip155:-1", "eip155:base"} {
+		t.Run("fails - "+network, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := networks.Lookup(network)
+			require.ErrorIs(t, err, api.ErrUnknownNetwork)
+		})
+	}
+
+	t.Run("fails - invalid registration", func(t *testing.T) {
+		t.Parallel()
+
+		require.Error(t, networks.Register(api.Network{Name: "private-l3"}))
+		require.Error(t, networks.Register(api.Network{ChainID: big.NewInt(1)}))
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceSize is the size of the nonces used by ERC-3009 and Permit2
+// authorizations.
+const NonceSize = 32
+
+// NonceScope identifies the contract and account that a nonce is used
+// with.  An authorization's nonce only needs to be unique within its
+// scope.
+type NonceScope struct {
+	ChainID *big.Int
+	// Contract is the contract that records the nonces that have been
+	// used (the token for ERC-3009 or the Permit2 contract.)
+	Contract common.Address
+	// Account is the authorizing account.
+	Account common.Address
+}
+
+// String returns a key that uniquely identifies the scope.
+func (s NonceScope) String() string {
+	return fmt.Sprintf("%s:%s:%s", s.ChainID, s.Contract.Hex(), s.Account.Hex())
+}
+
+// A NonceSource provides the nonces used by payment authorizations.
+// Implementations must not return the same nonce twice for a scope.
+type NonceSource interface {
+	Nonce(ctx context.Context, scope NonceScope) ([]byte, error)
+}
+
+// NonceFunc is a NonceSource that ignores the nonce's scope and can't
+// fail.
+type NonceFunc func() []byte
+
+// Nonce implements NonceSource.
+func (f NonceFunc) Nonce(_ context.Context, _ NonceScope) ([]byte, error) {
+	return f(), nil
+}
+
+// RandomNonce is a NonceSource that returns random nonces.  With 256
+// bits of randomness, a nonce won't be repeated unless the random number
+// generator fails.
+type RandomNonce struct{}
+
+// Nonce implements NonceSource.
+func (RandomNonce) Nonce(_ context.Context, _ NonceScope) ([]byte, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return nonce, nil
+}
+
+// DefaultNonce returns a random nonce.
+//
+// Deprecated: DefaultNonce returns a zero nonce if the random number
+// generator fails.  Use RandomNonce, which returns the error, instead.
+func DefaultNonce() []byte {
+	nonce, _ := RandomNonce{}.Nonce(context.Background(), NonceScope{})
+	if nonce == nil {
+		nonce = make([]byte, NonceSize)
+	}
+
+	return nonce
+}
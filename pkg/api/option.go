@@ -1,16 +1,42 @@
 package api
 
-import "time"
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultValidAfter is how far before the current time a payment's
+// authorization becomes valid when not set using WithValidityWindow.  This
+// accommodates facilitators whose clocks are behind the buyer's.
+const DefaultValidAfter = 10 * time.Minute
+
+// ErrPermitNonceUnavailable is returned when an EIP-2612 permit is
+// required but no PermitNonceFunc was provided using WithPermitNonceFunc.
+var ErrPermitNonceUnavailable = errors.New("EIP-2612 permit nonce unavailable")
+
+// PermitNonceFunc returns the owner's current EIP-2612 nonce for the
+// token (as returned by the token contract's nonces method.)
+type PermitNonceFunc func(ctx context.Context, token Token, owner common.Address) (*big.Int, error)
+
+// Options configures the payments created by a Payer.
 type Options struct {
-	nonceFunc NonceFunc
-	nowFunc   NowFunc
+	nonces     NonceSource
+	nowFunc    NowFunc
+	validAfter time.Duration
+	validFor   time.Duration
+
+	permitNonceFunc PermitNonceFunc
 }
 
 func NewOptions(opts ...Option) (*Options, error) {
 	options := &Options{
-		nonceFunc: DefaultNonce,
-		nowFunc:   time.Now,
+		nonces:     RandomNonce{},
+		nowFunc:    time.Now,
+		validAfter: DefaultValidAfter,
 	}
 
 	for _, opt := range opts {
@@ -22,11 +48,97 @@ func NewOptions(opts ...Option) (*Options, error) {
 	return options, nil
 }
 
+// Nonce returns a new nonce for the scope using the configured
+// NonceSource.
+func (o *Options) Nonce(ctx context.Context, scope NonceScope) ([]byte, error) {
+	return o.nonces.Nonce(ctx, scope)
+}
+
+// Now returns the current time using the configured NowFunc.
+func (o *Options) Now() time.Time {
+	return o.nowFunc()
+}
+
+// PermitNonce returns the owner's current EIP-2612 nonce for the token
+// using the configured PermitNonceFunc.
+func (o *Options) PermitNonce(ctx context.Context, token Token, owner common.Address) (*big.Int, error) {
+	if o.permitNonceFunc == nil {
+		return nil, ErrPermitNonceUnavailable
+	}
+
+	return o.permitNonceFunc(ctx, token, owner)
+}
+
+type clockOffsetKey struct{}
+
+// ContextWithClockOffset returns a copy of ctx that causes the validity
+// windows of authorizations created using it to be offset from the current
+// time by the provided duration (e.g. to match the clock of a seller whose
+// clock differs from the local one.)
+func ContextWithClockOffset(ctx context.Context, offset time.Duration) context.Context {
+	return context.WithValue(ctx, clockOffsetKey{}, offset)
+}
+
+// ClockOffset returns the offset set using ContextWithClockOffset or zero
+// if no offset has been set.
+func ClockOffset(ctx context.Context) time.Duration {
+	offset, _ := ctx.Value(clockOffsetKey{}).(time.Duration)
+
+	return offset
+}
+
+// Window returns the validity window of an authorization created now for
+// a seller that allows timeout to complete the payment.  The window opens
+// validAfter before now and closes after the shorter of timeout and the
+// duration set using WithValidityWindow.  Since an authorization shouldn't
+// outlive the request that it pays for, the window also closes no later
+// than the ctx's deadline.  The current time is adjusted by the ctx's
+// ClockOffset.
+func (o *Options) Window(ctx context.Context, timeout time.Duration) (time.Time, time.Time, error) {
+	now := o.Now().Add(ClockOffset(ctx))
+
+	if o.validFor > 0 && o.validFor < timeout {
+		timeout = o.validFor
+	}
+
+	expiry := now.Add(timeout)
+
+	// The deadline is measured by the local clock which might differ from
+	// the (possibly skew compensated) current time.
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := now.Add(time.Until(deadline)); remaining.Before(expiry) {
+			expiry = remaining
+		}
+	}
+
+	if !expiry.After(now) {
+		return time.Time{}, time.Time{}, context.DeadlineExceeded
+	}
+
+	return now.Add(-o.validAfter), expiry, nil
+}
+
 type Option func(*Options) error
 
 func WithNonceFunc(nonceFunc NonceFunc) Option {
+	// A nil NonceFunc would otherwise be stored as a non-nil NonceSource.
+	if nonceFunc == nil {
+		return WithNonceSource(nil)
+	}
+
+	return WithNonceSource(nonceFunc)
+}
+
+// WithNonceSource is an Option that sets the NonceSource used to create
+// the nonces of ERC-3009 and Permit2 authorizations.  By default, random
+// nonces are used.
+func WithNonceSource(nonces NonceSource) Option {
 	return func(o *Options) error {
-		o.nonceFunc = nonceFunc
+		if nonces == nil {
+			return errors.New("nonce source is required")
+		}
+
+		o.nonces = nonces
 
 		return nil
 	}
@@ -39,3 +151,37 @@ func WithNowFunc(nowFunc NowFunc) Option {
 		return nil
 	}
 }
+
+// WithValidityWindow is an Option that sets how far before the current
+// time an authorization becomes valid (validAfter) and the longest time
+// that it remains valid (validFor.)  A validFor of zero allows the
+// authorization to remain valid for the timeout requested by the seller.
+// Authorizations never remain valid longer than the seller's timeout.
+func WithValidityWindow(validAfter, validFor time.Duration) Option {
+	return func(o *Options) error {
+		if validAfter < 0 || validFor < 0 {
+			return errors.New("validity window durations must not be negative")
+		}
+
+		o.validAfter = validAfter
+		o.validFor = validFor
+
+		return nil
+	}
+}
+
+// WithPermitNonceFunc is an Option that provides the nonces needed to sign
+// EIP-2612 permits.  Unlike ERC-3009 and Permit2 nonces, which are chosen
+// by the signer, EIP-2612 nonces are sequential and must be read from the
+// token contract.
+func WithPermitNonceFunc(permitNonceFunc PermitNonceFunc) Option {
+	return func(o *Options) error {
+		if permitNonceFunc == nil {
+			return errors.New("permit nonce func is required")
+		}
+
+		o.permitNonceFunc = permitNonceFunc
+
+		return nil
+	}
+}
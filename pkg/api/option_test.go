@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+func TestNewOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes - nonce func", func(t *testing.T) {
+		t.Parallel()
+
+		options, err := api.NewOptions(api.WithNonceFunc(func() []byte {
+			return []byte{0x01}
+		}))
+		require.NoError(t, err)
+
+		nonce, err := options.Nonce(t.Context(), api.NonceScope{})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x01}, nonce)
+	})
+
+	t.Run("passes - permit nonce func", func(t *testing.T) {
+		t.Parallel()
+
+		options, err := api.NewOptions(api.WithPermitNonceFunc(func(context.Context, api.Token, common.Address) (*big.Int, error) {
+			return big.NewInt(7), nil
+		}))
+		require.NoError(t, err)
+
+		nonce, err := options.PermitNonce(t.Context(), api.Token{}, common.Address{})
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(7), nonce)
+	})
+
+	t.Run("fails - nil nonce func", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := api.NewOptions(api.WithNonceFunc(nil))
+		require.EqualError(t, err, "nonce source is required")
+	})
+
+	t.Run("fails - nil nonce source", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := api.NewOptions(api.WithNonceSource(nil))
+		require.EqualError(t, err, "nonce source is required")
+	})
+
+	t.Run("fails - nil permit nonce func", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := api.NewOptions(api.WithPermitNonceFunc(nil))
+		require.EqualError(t, err, "permit nonce func is required")
+	})
+}
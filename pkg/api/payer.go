@@ -1,7 +1,9 @@
 package api
 
 import (
-	"crypto/rand"
+	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/coinbase/x402/go/pkg/types"
@@ -11,6 +13,9 @@ type Scheme string
 
 const (
 	SchemeExact Scheme = "exact"
+	// SchemeUpto authorizes the seller to charge up to the required amount
+	// and is used to pay for resources whose price depends on usage.
+	SchemeUpto Scheme = "upto"
 )
 
 // Payer represents types that can be registered and make payments on the
@@ -26,6 +31,65 @@ type Payer interface {
 	Scheme() Scheme
 }
 
+// A ContextPayer is a Payer that accepts a context.Context which can be
+// used to cancel the creation of a payment (for instance, while waiting
+// on a remote Signer).
+type ContextPayer interface {
+	Payer
+	// PayContext is like Pay but aborts with the context's error if ctx is
+	// done before the payment is signed.  Implementations should also
+	// ensure that the payment's authorization expires no later than the
+	// ctx's deadline.
+	PayContext(ctx context.Context, requirements types.PaymentRequirements) (*types.PaymentPayload, error)
+}
+
+// A PayloadPayer is a ContextPayer that can make payments whose payload
+// can't be represented by a types.PaymentPayload (which always holds an
+// ERC-3009 authorization), such as Permit2 or Solana payments.  Its Pay
+// and PayContext methods return ErrUnsupportedPayload for such payments.
+type PayloadPayer interface {
+	ContextPayer
+	// PayPayload is like PayContext but returns a PaymentPayload that can
+	// hold the payload of any scheme and network.
+	PayPayload(ctx context.Context, requirements types.PaymentRequirements) (*PaymentPayload, error)
+}
+
+// PayContext creates a payment using payer.PayPayload if the payer is a
+// PayloadPayer or payer.PayContext if the payer is a ContextPayer.
+// Otherwise, the ctx is checked before calling payer.Pay.
+func PayContext(ctx context.Context, payer Payer, requirements types.PaymentRequirements) (*PaymentPayload, error) {
+	if p, ok := payer.(PayloadPayer); ok {
+		return p.PayPayload(ctx, requirements)
+	}
+
+	var (
+		payment *types.PaymentPayload
+		err     error
+	)
+
+	if p, ok := payer.(ContextPayer); ok {
+		payment, err = p.PayContext(ctx, requirements)
+	} else if err = ctx.Err(); err == nil {
+		payment, err = payer.Pay(requirements)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPaymentPayload(payment), nil
+}
+
+// A NetworkPayer is a Payer that is able to report whether it can make
+// payments on a given network.  Payers that don't implement this interface
+// are assumed to support every network they're registered for.
+type NetworkPayer interface {
+	Payer
+	// Supports returns true if the Payer is able to make payments on the
+	// named network.
+	Supports(network string) bool
+}
+
 type Signature string
 
 // PaymentRequest represents the body of a 402 Payment Required response.
@@ -35,17 +99,193 @@ type PaymentRequest struct {
 	Accepts     []types.PaymentRequirements `json:"accepts"`
 }
 
-type NonceFunc func() []byte
+// ResourceInfo describes the resource that a version 2 payment is made
+// for.
+type ResourceInfo struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
 
-type NowFunc func() time.Time
+// PaymentRequirementsV2 represents one of the means of payment accepted by
+// a seller using version 2 of the x402 protocol.  Networks are identified
+// using CAIP-2 chain IDs (e.g. eip155:8453.)
+type PaymentRequirementsV2 struct {
+	Scheme            string           `json:"scheme"`
+	Network           string           `json:"network"`
+	Amount            string           `json:"amount"`
+	Asset             string           `json:"asset"`
+	PayTo             string           `json:"payTo"`
+	MaxTimeoutSeconds int              `json:"maxTimeoutSeconds"`
+	Extra             *json.RawMessage `json:"extra,omitempty"`
+}
+
+// PaymentRequiredV2 represents the (base64-encoded) value of the
+// PAYMENT-REQUIRED header of a version 2 402 Payment Required response.
+type PaymentRequiredV2 struct {
+	X402Version int                     `json:"x402Version"`
+	Err         string                  `json:"error,omitempty"`
+	Resource    *ResourceInfo           `json:"resource,omitempty"`
+	Accepts     []PaymentRequirementsV2 `json:"accepts"`
+	Extensions  json.RawMessage         `json:"extensions,omitempty"`
+}
+
+// PaymentPayloadV2 represents the (base64-encoded) value of the
+// PAYMENT-SIGNATURE header sent with a version 2 payment.  Accepted echoes
+// the seller's payment requirements that were paid exactly as they were
+// received.
+type PaymentPayloadV2 struct {
+	X402Version int             `json:"x402Version"`
+	Resource    *ResourceInfo   `json:"resource,omitempty"`
+	Accepted    json.RawMessage `json:"accepted"`
+	Payload     any             `json:"payload"`
+	Extensions  json.RawMessage `json:"extensions,omitempty"`
+}
+
+// PaymentPayload represents a signed payment.  It has the same JSON
+// encoding as types.PaymentPayload but Payload can hold the payload of
+// any scheme and network (e.g. a *types.ExactEvmPayload or an
+// *UptoEvmPayload.)
+type PaymentPayload struct {
+	X402Version int    `json:"x402Version"`
+	Scheme      string `json:"scheme"`
+	Network     string `json:"network"`
+	Payload     any    `json:"payload"`
+}
 
-func DefaultNonce() []byte {
-	nonce := make([]byte, 32)
-	_, _ = rand.Read(nonce)
+// NewPaymentPayload returns the PaymentPayload equivalent to the
+// types.PaymentPayload.
+func NewPaymentPayload(payment *types.PaymentPayload) *PaymentPayload {
+	if payment == nil {
+		return nil
+	}
 
-	return nonce
+	out := &PaymentPayload{
+		X402Version: payment.X402Version,
+		Scheme:      payment.Scheme,
+		Network:     payment.Network,
+	}
+
+	// Avoid storing a typed nil in Payload.
+	if payment.Payload != nil {
+		out.Payload = payment.Payload
+	}
+
+	return out
 }
 
+// Types returns the types.PaymentPayload equivalent to the PaymentPayload.
+// If the payment isn't an ERC-3009 authorization, ErrUnsupportedPayload is
+// returned.
+func (p *PaymentPayload) Types() (*types.PaymentPayload, error) {
+	payload, ok := p.ExactEvm()
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedPayload, p.Payload)
+	}
+
+	return &types.PaymentPayload{
+		X402Version: p.X402Version,
+		Scheme:      p.Scheme,
+		Network:     p.Network,
+		Payload:     payload,
+	}, nil
+}
+
+// ExactEvm returns the payload of a payment made using the "exact" scheme
+// on an EVM-compatible network.
+func (p *PaymentPayload) ExactEvm() (*types.ExactEvmPayload, bool) {
+	payload, ok := p.Payload.(*types.ExactEvmPayload)
+
+	return payload, ok
+}
+
+// UptoEvm returns the payload of a payment made using the "upto" scheme
+// on an EVM-compatible network.
+func (p *PaymentPayload) UptoEvm() (*UptoEvmPayload, bool) {
+	return p.Permit2()
+}
+
+// Permit returns the payload of a payment made using an EIP-2612 permit.
+func (p *PaymentPayload) Permit() (*PermitEvmPayload, bool) {
+	payload, ok := p.Payload.(*PermitEvmPayload)
+
+	return payload, ok
+}
+
+// Permit2 returns the payload of a payment made using a Permit2
+// PermitTransferFrom.
+func (p *PaymentPayload) Permit2() (*Permit2EvmPayload, bool) {
+	payload, ok := p.Payload.(*Permit2EvmPayload)
+
+	return payload, ok
+}
+
+// ExactSvm returns the payload of a payment made using the "exact" scheme
+// on Solana.
+func (p *PaymentPayload) ExactSvm() (*ExactSvmPayload, bool) {
+	payload, ok := p.Payload.(*ExactSvmPayload)
+
+	return payload, ok
+}
+
+// ExactSvmPayload is the payload of an "exact" payment on Solana.  The
+// transaction is base64-encoded and has been signed by the buyer but not
+// by the fee payer, which signs and submits it when the payment is
+// settled.
+type ExactSvmPayload struct {
+	Transaction string `json:"transaction"`
+}
+
+// PermitEvmPayload is the payload of an "exact" payment on an
+// EVM-compatible network for a token that supports EIP-2612 permits.  The
+// spender uses the permit to approve, then transfer, the value.
+type PermitEvmPayload struct {
+	Signature     string               `json:"signature"`
+	Authorization *PermitAuthorization `json:"authorization"`
+}
+
+// PermitAuthorization describes an EIP-2612 Permit.  The value, nonce and
+// deadline are decimal strings.
+type PermitAuthorization struct {
+	Owner    string `json:"owner"`
+	Spender  string `json:"spender"`
+	Value    string `json:"value"`
+	Nonce    string `json:"nonce"`
+	Deadline string `json:"deadline"`
+}
+
+// Permit2EvmPayload is the payload of a payment on an EVM-compatible
+// network made using a signed Permit2 PermitTransferFrom that allows the
+// spender to transfer any amount up to the permitted amount, once, before
+// the deadline.
+type Permit2EvmPayload struct {
+	Signature     string                `json:"signature"`
+	Authorization *Permit2Authorization `json:"authorization"`
+}
+
+// UptoEvmPayload is the payload of a payment made using the "upto" scheme
+// on an EVM-compatible network.
+type UptoEvmPayload = Permit2EvmPayload
+
+// Permit2Authorization describes a Permit2 PermitTransferFrom.  Amounts,
+// the nonce and the deadline are decimal strings.
+type Permit2Authorization struct {
+	From      string            `json:"from"`
+	Permitted Permit2Permission `json:"permitted"`
+	Spender   string            `json:"spender"`
+	Nonce     string            `json:"nonce"`
+	Deadline  string            `json:"deadline"`
+}
+
+// Permit2Permission is the token and maximum amount permitted to be
+// transferred by a Permit2Authorization.
+type Permit2Permission struct {
+	Token  string `json:"token"`
+	Amount string `json:"amount"`
+}
+
+type NowFunc func() time.Time
+
 func DefaultNow() NowFunc {
 	return time.Now
 }
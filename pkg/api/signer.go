@@ -1,6 +1,12 @@
 package api
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	"context"
+	"crypto/ed25519"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
 
 // A Signer is implemented by types that can produce a ECDSA signature
 // of the provided digestHash.
@@ -8,6 +14,29 @@ type Signer interface {
 	Sign(digestHash []byte) ([]byte, error)
 }
 
+// A ContextSigner is a Signer that accepts a context.Context which can be
+// used to cancel a slow (for instance, remote) signing operation.
+type ContextSigner interface {
+	Signer
+
+	SignContext(ctx context.Context, digestHash []byte) ([]byte, error)
+}
+
+// SignContext signs the digestHash using signer.SignContext if the signer
+// is a ContextSigner.  Otherwise, the ctx is checked before calling
+// signer.Sign.
+func SignContext(ctx context.Context, signer Signer, digestHash []byte) ([]byte, error) {
+	if s, ok := signer.(ContextSigner); ok {
+		return s.SignContext(ctx, digestHash)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return signer.Sign(digestHash)
+}
+
 // An EVMSigner is a Signer that operates on behalf of an Ethereum account
 // and therefore has an address.
 type EVMSigner interface {
@@ -15,3 +44,46 @@ type EVMSigner interface {
 
 	Address() common.Address
 }
+
+// A ContractSigner is an EVMSigner whose Address is a smart-contract wallet
+// rather than an externally owned account.  Signatures made on behalf of
+// the wallet are validated by its EIP-1271 isValidSignature method (after
+// deploying it if the signature is wrapped as described by ERC-6492), so
+// payers use them exactly as returned by Sign and don't expect them to
+// recover to the wallet's Address.
+type ContractSigner interface {
+	EVMSigner
+	// IsContract returns true if the signer's Address is a smart-contract
+	// wallet.
+	IsContract() bool
+}
+
+// IsContractSigner returns true if the signer makes signatures on behalf
+// of a smart-contract wallet.
+func IsContractSigner(signer Signer) bool {
+	s, ok := signer.(ContractSigner)
+
+	return ok && s.IsContract()
+}
+
+// A TypedDataSigner is an EVMSigner that receives the full EIP-712 typed
+// data of each payment rather than an opaque digest.  This allows signers
+// that enforce policies or ask a human for approval to know what they're
+// authorizing.  Payers prefer SignTypedData over Sign when the signer
+// implements this interface.
+type TypedDataSigner interface {
+	EVMSigner
+	// SignTypedData returns the signature of the typedData's EIP-712 hash
+	// with a V of 27 or 28.
+	SignTypedData(ctx context.Context, typedData apitypes.TypedData) ([]byte, error)
+}
+
+// An SVMSigner is a Signer that operates on behalf of a Solana account and
+// therefore has an Ed25519 public key.  Unlike an EVMSigner, which signs a
+// digest, an SVMSigner's Sign method receives the serialized transaction
+// message and returns its Ed25519 signature.
+type SVMSigner interface {
+	Signer
+
+	PublicKey() ed25519.PublicKey
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// MaxPaymentTimeout is the longest timeout a seller may request for a
+// payment.  Longer timeouts would result in authorizations that remain
+// valid long after the request they pay for has completed.
+const MaxPaymentTimeout = 24 * time.Hour
+
+// ErrInvalidRequirements is returned (wrapped in a ValidationError) when
+// a seller's payment requirements are malformed.
+var ErrInvalidRequirements = errors.New("invalid payment requirements")
+
+// FieldError describes a single invalid field of a seller's payment
+// requirements.
+type FieldError struct {
+	// Field is the JSON name of the invalid field (e.g. maxAmountRequired.)
+	Field string
+	// Reason describes why the field's value is invalid.
+	Reason string
+}
+
+// Error implements error.
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationError lists each of the invalid fields found in a seller's
+// payment requirements.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, field.Error())
+	}
+
+	return fmt.Sprintf("%s: %s", ErrInvalidRequirements, strings.Join(fields, "; "))
+}
+
+// Is allows the ValidationError to match ErrInvalidRequirements when using
+// errors.Is.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidRequirements
+}
+
+// Add records an invalid field.
+func (e *ValidationError) Add(field, reason string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{
+		Field:  field,
+		Reason: fmt.Sprintf(reason, args...),
+	})
+}
+
+// Err returns the ValidationError if any invalid fields were recorded and
+// nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// A Validator is a Payer that is able to check whether payment
+// requirements are well-formed before attempting to pay them.  Payment
+// requirements that fail validation are treated as unpayable.
+type Validator interface {
+	Payer
+	// Validate returns a *ValidationError describing each invalid field of
+	// the requirements or nil if the requirements can be paid.
+	Validate(requirements types.PaymentRequirements) error
+}
+
+// ValidateRequirements performs the validation that applies to payment
+// requirements regardless of their scheme or network and records any
+// invalid fields in verr.  Validators will typically call this function
+// before checking the fields specific to their scheme and network.
+func ValidateRequirements(verr *ValidationError, requirements types.PaymentRequirements) {
+	if requirements.Scheme == "" {
+		verr.Add("scheme", "is required")
+	}
+
+	if requirements.Network == "" {
+		verr.Add("network", "is required")
+	}
+
+	if requirements.PayTo == "" {
+		verr.Add("payTo", "is required")
+	}
+
+	if requirements.Asset == "" {
+		verr.Add("asset", "is required")
+	}
+
+	if !isDecimal(requirements.MaxAmountRequired) {
+		verr.Add("maxAmountRequired", "%q is not a decimal amount", requirements.MaxAmountRequired)
+	} else if amount, _ := new(big.Int).SetString(requirements.MaxAmountRequired, 10); amount.Sign() <= 0 || amount.BitLen() > 256 {
+		verr.Add("maxAmountRequired", "%s is out of range", requirements.MaxAmountRequired)
+	}
+
+	if requirements.MaxTimeoutSeconds <= 0 || requirements.MaxTimeoutSeconds > int(MaxPaymentTimeout/time.Second) {
+		verr.Add("maxTimeoutSeconds", "%d is not between 1 and %d", requirements.MaxTimeoutSeconds, int(MaxPaymentTimeout/time.Second))
+	}
+
+	if requirements.Extra != nil {
+		var extra map[string]json.RawMessage
+		if err := json.Unmarshal(*requirements.Extra, &extra); err != nil {
+			verr.Add("extra", "is not a JSON object")
+		}
+	}
+}
+
+// isDecimal returns true if s is a non-empty string of decimal digits.
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
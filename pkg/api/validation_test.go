@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+func TestValidateRequirements(t *testing.T) {
+	t.Parallel()
+
+	valid := func() types.PaymentRequirements {
+		extra := json.RawMessage(`{"name":"USD Coin","version":"2"}`)
+
+		return types.PaymentRequirements{
+			Scheme:            "exact",
+			Network:           "base",
+			MaxAmountRequired: "10000",
+			PayTo:             "0x60ac86571E55F9735F00cE9e28361d203977B260",
+			MaxTimeoutSeconds: 60,
+			Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+			Extra:             &extra,
+		}
+	}
+
+	t.Run("passes", func(t *testing.T) {
+		t.Parallel()
+
+		var verr api.ValidationError
+
+		api.ValidateRequirements(&verr, valid())
+		require.NoError(t, verr.Err())
+	})
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(*types.PaymentRequirements)
+		fields []string
+	}{
+		{
+			name:   "missing fields",
+			mutate: func(r *types.PaymentRequirements) { *r = types.PaymentRequirements{} },
+			fields: []string{"scheme", "network", "payTo", "asset", "maxAmountRequired", "maxTimeoutSeconds"},
+		},
+		{
+			name:   "signed amount",
+			mutate: func(r *types.PaymentRequirements) { r.MaxAmountRequired = "-1" },
+			fields: []string{"maxAmountRequired"},
+		},
+		{
+			name:   "zero amount",
+			mutate: func(r *types.PaymentRequirements) { r.MaxAmountRequired = "0" },
+			fields: []string{"maxAmountRequired"},
+		},
+		{
+			name:   "amount too large",
+			mutate: func(r *types.PaymentRequirements) { r.MaxAmountRequired = "1" + strings.Repeat("0", 78) },
+			fields: []string{"maxAmountRequired"},
+		},
+		{
+			name:   "timeout too long",
+			mutate: func(r *types.PaymentRequirements) { r.MaxTimeoutSeconds = 30 * 24 * 60 * 60 },
+			fields: []string{"maxTimeoutSeconds"},
+		},
+		{
+			name: "extra not an object",
+			mutate: func(r *types.PaymentRequirements) {
+				extra := json.RawMessage(`"USD Coin"`)
+				r.Extra = &extra
+			},
+			fields: []string{"extra"},
+		},
+	} {
+		t.Run("fails - "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requirements := valid()
+			tc.mutate(&requirements)
+
+			var verr api.ValidationError
+
+			api.ValidateRequirements(&verr, requirements)
+
+			err := verr.Err()
+			require.ErrorIs(t, err, api.ErrInvalidRequirements)
+
+			var validationErr *api.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+
+			fields := make([]string, 0, len(validationErr.Fields))
+			for _, field := range validationErr.Fields {
+				fields = append(fields, field.Field)
+			}
+
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package buyer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/coinbase/x402/go/pkg/types"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+const (
+	headerPayment         = "X-Payment"
+	headerPaymentResponse = "X-Payment-Response"
+
+	headerPaymentRequiredV2  = "Payment-Required"
+	headerPaymentSignatureV2 = "Payment-Signature"
+	headerPaymentResponseV2  = "Payment-Response"
+)
+
+// paymentRequired is a seller's 402 Payment Required response normalized
+// so that the payment requirements of each version of the x402 protocol
+// can be selected and paid in the same way.
+type paymentRequired struct {
+	version  int
+	err      string
+	accepts  []types.PaymentRequirements
+	resource *api.ResourceInfo
+	// raw holds the version 2 requirements exactly as they were received
+	// and shares its indexes with accepts.
+	raw []json.RawMessage
+}
+
+// offer is one of the seller's payment requirements along with the details
+// needed to pay it using the seller's version of the x402 protocol.
+type offer struct {
+	version      int
+	requirements types.PaymentRequirements
+	accepted     json.RawMessage
+	resource     *api.ResourceInfo
+}
+
+// parsePaymentRequired detects the version of the x402 protocol used by
+// the seller and decodes its payment requirements.  Version 2 sellers
+// provide their requirements in the PAYMENT-REQUIRED header while version
+// 1 sellers provide them in the response's body.
+func parsePaymentRequired(header http.Header, body []byte) (*paymentRequired, error) {
+	if value := header.Get(headerPaymentRequiredV2); value != "" {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode payment required header: %w", err)
+		}
+
+		return parsePaymentRequiredV2(data)
+	}
+
+	var version struct {
+		X402Version int `json:"x402Version"`
+	}
+
+	if err := json.Unmarshal(body, &version); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment request: %w", err)
+	}
+
+	if version.X402Version == 2 {
+		return parsePaymentRequiredV2(body)
+	}
+
+	var paymentRequest api.PaymentRequest
+	if err := json.Unmarshal(body, &paymentRequest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment request: %w", err)
+	}
+
+	return &paymentRequired{
+		version: 1,
+		err:     paymentRequest.Err,
+		accepts: paymentRequest.Accepts,
+	}, nil
+}
+
+func parsePaymentRequiredV2(data []byte) (*paymentRequired, error) {
+	var raw struct {
+		api.PaymentRequiredV2
+
+		Accepts []json.RawMessage `json:"accepts"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment required: %w", err)
+	}
+
+	if raw.X402Version != 2 {
+		return nil, fmt.Errorf("unsupported x402 version: %d", raw.X402Version)
+	}
+
+	pr := &paymentRequired{
+		version:  2,
+		err:      raw.Err,
+		resource: raw.Resource,
+		raw:      raw.Accepts,
+	}
+
+	for _, accepted := range raw.Accepts {
+		var requirements api.PaymentRequirementsV2
+		if err := json.Unmarshal(accepted, &requirements); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payment requirements: %w", err)
+		}
+
+		normalized := types.PaymentRequirements{
+			Scheme:            requirements.Scheme,
+			Network:           requirements.Network,
+			MaxAmountRequired: requirements.Amount,
+			PayTo:             requirements.PayTo,
+			MaxTimeoutSeconds: requirements.MaxTimeoutSeconds,
+			Asset:             requirements.Asset,
+			Extra:             requirements.Extra,
+		}
+
+		if raw.Resource != nil {
+			normalized.Resource = raw.Resource.URL
+			normalized.Description = raw.Resource.Description
+			normalized.MimeType = raw.Resource.MimeType
+		}
+
+		pr.accepts = append(pr.accepts, normalized)
+	}
+
+	return pr, nil
+}
+
+// offers returns an offer for each of the selected requirements.
+func (p *paymentRequired) offers(selected []types.PaymentRequirements) []offer {
+	out := make([]offer, 0, len(selected))
+
+	for _, requirements := range selected {
+		o := offer{
+			version:      p.version,
+			requirements: requirements,
+			resource:     p.resource,
+		}
+
+		if p.version == 2 {
+			idx := slices.IndexFunc(p.accepts, func(r types.PaymentRequirements) bool {
+				return reflect.DeepEqual(r, requirements)
+			})
+			if idx < 0 {
+				// The Selector returned requirements that weren't
+				// offered by the seller.
+				continue
+			}
+
+			o.accepted = p.raw[idx]
+		}
+
+		out = append(out, o)
+	}
+
+	return out
+}
+
+// encode returns the name of the header that carries the payment to the
+// seller along with the JSON that will be base64-encoded as its value.
+func (o offer) encode(payment *api.PaymentPayload) (string, []byte, error) {
+	if o.version != 2 {
+		data, err := json.Marshal(payment)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal payment: %w", err)
+		}
+
+		return headerPayment, data, nil
+	}
+
+	data, err := json.Marshal(api.PaymentPayloadV2{
+		X402Version: 2,
+		Resource:    o.resource,
+		Accepted:    o.accepted,
+		Payload:     payment.Payload,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal payment: %w", err)
+	}
+
+	return headerPaymentSignatureV2, data, nil
+}
+
+// settlementHeader returns the value of the header describing the
+// settlement of a payment for either version of the x402 protocol.
+func settlementHeader(header http.Header) string {
+	if value := header.Get(headerPaymentResponseV2); value != "" {
+		return value
+	}
+
+	return header.Get(headerPaymentResponse)
+}
@@ -0,0 +1,149 @@
+package buyer_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestProtocolVersion2(t *testing.T) {
+	t.Parallel()
+
+	const (
+		accepted   = `{"scheme":"exact","network":"eip155:8453","amount":"10000","asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"extra":{"name":"USD Coin","version":"2"}}`
+		payReq     = `{"x402Version":2,"error":"PAYMENT-SIGNATURE header is required","resource":{"url":"https://example.com","description":"A premium programming joke","mimeType":"text/plain"},"accepts":[` + accepted + `]}`
+		settlement = `{"success":true,"transaction":"0x5b1f6e7a8c0d2e4f","network":"eip155:8453"}`
+	)
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		body   string
+	}{
+		{
+			name: "header",
+			header: http.Header{
+				"Payment-Required": []string{base64.StdEncoding.EncodeToString([]byte(payReq))},
+			},
+			body: "{}",
+		},
+		{
+			name:   "body",
+			header: http.Header{},
+			body:   payReq,
+		},
+	} {
+		t.Run("passes - payment required in "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			respIn1 := &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Payment-Response": []string{base64.StdEncoding.EncodeToString([]byte(settlement))},
+				},
+				Body: io.NopCloser(strings.NewReader("Response body")),
+			}
+
+			respIn2 := &http.Response{
+				StatusCode: http.StatusPaymentRequired,
+				Header:     tc.header,
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+
+			next := newMockTransport(t, respIn2, respIn1)
+			trans, err := buyer.NewTransport(next, signer)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
+			require.NoError(t, err)
+
+			respOut, err := trans.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, respIn1, respOut)
+
+			t.Cleanup(func() {
+				require.NoError(t, respOut.Body.Close())
+			})
+
+			require.Len(t, next.headers, 2)
+			assert.Empty(t, next.headers[1].Get("X-Payment"))
+
+			data, err := base64.StdEncoding.DecodeString(next.headers[1].Get("Payment-Signature"))
+			require.NoError(t, err)
+
+			var payload struct {
+				X402Version int             `json:"x402Version"`
+				Resource    map[string]any  `json:"resource"`
+				Accepted    json.RawMessage `json:"accepted"`
+				Payload     struct {
+					Signature     string            `json:"signature"`
+					Authorization map[string]string `json:"authorization"`
+				} `json:"payload"`
+			}
+
+			require.NoError(t, json.Unmarshal(data, &payload))
+			assert.Equal(t, 2, payload.X402Version)
+			assert.Equal(t, "https://example.com", payload.Resource["url"])
+			assert.JSONEq(t, accepted, string(payload.Accepted))
+			assert.NotEmpty(t, payload.Payload.Signature)
+			assert.Equal(t, signer.Address().Hex(), payload.Payload.Authorization["from"])
+			assert.Equal(t, "10000", payload.Payload.Authorization["value"])
+
+			payment, ok := buyer.PaymentFromResponse(respOut)
+			require.True(t, ok)
+			assert.Equal(t, 2, payment.Payload.X402Version)
+			assert.Equal(t, "eip155:8453", payment.Requirements.Network)
+			assert.Equal(t, "10000", payment.Requirements.MaxAmountRequired)
+			require.NotNil(t, payment.Settlement)
+			assert.Equal(t, "0x5b1f6e7a8c0d2e4f", payment.Settlement.Transaction)
+		})
+	}
+
+	t.Run("fails - rejected", func(t *testing.T) {
+		t.Parallel()
+
+		const rejectReq = `{"x402Version":2,"error":"insufficient_funds","accepts":[]}`
+
+		respIn2 := &http.Response{
+			StatusCode: http.StatusPaymentRequired,
+			Header: http.Header{
+				"Payment-Required": []string{base64.StdEncoding.EncodeToString([]byte(payReq))},
+			},
+			Body: io.NopCloser(strings.NewReader("{}")),
+		}
+
+		respIn3 := &http.Response{
+			StatusCode: http.StatusPaymentRequired,
+			Header: http.Header{
+				"Payment-Required": []string{base64.StdEncoding.EncodeToString([]byte(rejectReq))},
+			},
+			Body: io.NopCloser(strings.NewReader("{}")),
+		}
+
+		next := newMockTransport(t, respIn2, respIn3)
+		trans, err := buyer.NewTransport(next, signer)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
+		require.NoError(t, err)
+
+		_, err = trans.RoundTrip(req)
+
+		var rejectedErr *buyer.PaymentRejectedError
+		require.ErrorAs(t, err, &rejectedErr)
+		assert.Equal(t, "insufficient_funds", rejectedErr.Reason)
+	})
+}
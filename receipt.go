@@ -0,0 +1,109 @@
+package buyer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/types"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// Payment is a receipt describing the x402 payment that was made to obtain
+// an http.Response.
+type Payment struct {
+	// Requirements are the payment requirements offered by the seller that
+	// were selected for payment.
+	Requirements types.PaymentRequirements
+	// Payload is the signed payment that was sent to the seller, whatever
+	// its scheme, network and transfer method.  Use its Types method to
+	// obtain the equivalent types.PaymentPayload.
+	Payload *api.PaymentPayload
+	// Settlement is the seller's response describing the settlement of
+	// the payment.  Settlement is nil if the seller did not provide a
+	// settlement response or if it could not be decoded.
+	Settlement *types.SettleResponse
+	// Charged is the amount that the seller charged once it's known.  For
+	// the "exact" scheme, the required amount is charged when the payment
+	// is settled.  For the "upto" scheme, the seller reports the amount it
+	// charged (which may be less than the authorized maximum) in its
+	// settlement response.  Charged is nil if the amount isn't known.
+	Charged *big.Int
+}
+
+type paymentKey struct{}
+
+// PaymentFromResponse returns the Payment that was made to obtain the
+// provided http.Response.  If no payment was made, false is returned.
+func PaymentFromResponse(resp *http.Response) (*Payment, bool) {
+	if resp == nil || resp.Request == nil {
+		return nil, false
+	}
+
+	payment, ok := resp.Request.Context().Value(paymentKey{}).(*Payment)
+
+	return payment, ok
+}
+
+// withPayment attaches the Payment to the http.Response so that it can be
+// retrieved using PaymentFromResponse.
+func withPayment(resp *http.Response, req *http.Request, payment *Payment) {
+	if resp.Request != nil {
+		req = resp.Request
+	}
+
+	resp.Request = req.WithContext(context.WithValue(req.Context(), paymentKey{}, payment))
+}
+
+// decodeSettlement decodes the base64-encoded JSON value of the
+// X-Payment-Response header along with the amount charged by the seller
+// (or nil if the seller didn't report an amount.)
+func decodeSettlement(header string) (*types.SettleResponse, *big.Int, error) {
+	data, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode settlement response: %w", err)
+	}
+
+	var settlement struct {
+		types.SettleResponse
+
+		Amount string `json:"amount"`
+	}
+
+	if err := json.Unmarshal(data, &settlement); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal settlement response: %w", err)
+	}
+
+	charged, ok := new(big.Int).SetString(settlement.Amount, 10)
+	if !ok {
+		charged = nil
+	}
+
+	return &settlement.SettleResponse, charged, nil
+}
+
+// charged returns the amount charged for a settled payment.
+func charged(requirements types.PaymentRequirements, settlement *types.SettleResponse, reported *big.Int) *big.Int {
+	if settlement == nil || !settlement.Success {
+		return nil
+	}
+
+	if reported != nil {
+		return reported
+	}
+
+	if api.Scheme(requirements.Scheme) != api.SchemeExact {
+		return nil
+	}
+
+	amount, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+	if !ok {
+		return nil
+	}
+
+	return amount
+}
@@ -0,0 +1,143 @@
+package buyer_test
+
+import (
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestPaymentFromResponse(t *testing.T) {
+	t.Parallel()
+
+	const settlement = `{"success":true,"transaction":"0x5b1f6e7a8c0d2e4f","network":"base","payer":"0x7840586eE7C215aE14599655b7c96ce23B7A9662"}`
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	t.Run("passes - payment with settlement", func(t *testing.T) {
+		t.Parallel()
+
+		respIn := okResponse()
+		respIn.Header.Set("X-Payment-Response", base64.StdEncoding.EncodeToString([]byte(settlement)))
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), respIn})
+
+		respOut, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+
+		payment, ok := buyer.PaymentFromResponse(respOut)
+		require.True(t, ok)
+		assert.Equal(t, "base", payment.Requirements.Network)
+		payload, ok := payment.Payload.ExactEvm()
+		require.True(t, ok)
+		assert.Equal(t, signer.Address().Hex(), payload.Authorization.From)
+		require.NotNil(t, payment.Settlement)
+		assert.True(t, payment.Settlement.Success)
+		assert.Equal(t, "0x5b1f6e7a8c0d2e4f", payment.Settlement.Transaction)
+		require.NotNil(t, payment.Settlement.Payer)
+		assert.Equal(t, "0x7840586eE7C215aE14599655b7c96ce23B7A9662", *payment.Settlement.Payer)
+		require.NotNil(t, payment.Charged)
+		assert.Equal(t, "10000", payment.Charged.String())
+	})
+
+	t.Run("passes - upto payment with charged amount", func(t *testing.T) {
+		t.Parallel()
+
+		const (
+			uptoReq        = `{"accepts":[{"scheme":"upto","network":"base","maxAmountRequired":"250000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"}],"error":"X-PAYMENT header is required","x402Version":1}`
+			uptoSettlement = `{"success":true,"transaction":"0x5b1f6e7a8c0d2e4f","network":"base","amount":"1234"}`
+		)
+
+		respIn := okResponse()
+		respIn.Header.Set("X-Payment-Response", base64.StdEncoding.EncodeToString([]byte(uptoSettlement)))
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(uptoReq), respIn}, buyer.WithUpto())
+
+		respOut, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+
+		payment, ok := buyer.PaymentFromResponse(respOut)
+		require.True(t, ok)
+
+		payload, ok := payment.Payload.UptoEvm()
+		require.True(t, ok)
+		assert.Equal(t, "250000", payload.Authorization.Permitted.Amount)
+		require.NotNil(t, payment.Charged)
+		assert.Equal(t, "1234", payment.Charged.String())
+	})
+
+	t.Run("fails - upto maximum exceeds budget", func(t *testing.T) {
+		t.Parallel()
+
+		const uptoReq = `{"accepts":[{"scheme":"upto","network":"base","maxAmountRequired":"250000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"}],"error":"X-PAYMENT header is required","x402Version":1}`
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(uptoReq)}, buyer.WithUpto(), buyer.WithMaxPayment(big.NewInt(100000)))
+
+		_, err := doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrBudgetExceeded)
+	})
+
+	t.Run("fails - upto scheme not enabled", func(t *testing.T) {
+		t.Parallel()
+
+		const uptoReq = `{"accepts":[{"scheme":"upto","network":"base","maxAmountRequired":"250000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"}],"error":"X-PAYMENT header is required","x402Version":1}`
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(uptoReq)})
+
+		_, err := doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrNoAcceptablePayment)
+	})
+
+	t.Run("passes - payment with invalid settlement", func(t *testing.T) {
+		t.Parallel()
+
+		respIn := okResponse()
+		respIn.Header.Set("X-Payment-Response", "not base64")
+
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), respIn})
+
+		respOut, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+
+		payment, ok := buyer.PaymentFromResponse(respOut)
+		require.True(t, ok)
+		assert.NotNil(t, payment.Payload)
+		assert.Nil(t, payment.Settlement)
+	})
+
+	t.Run("passes - no payment required", func(t *testing.T) {
+		t.Parallel()
+
+		trans, _ := newTestTransport(t, []*http.Response{okResponse()})
+
+		respOut, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, respOut.Body.Close())
+		})
+
+		_, ok := buyer.PaymentFromResponse(respOut)
+		assert.False(t, ok)
+	})
+}
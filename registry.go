@@ -0,0 +1,124 @@
+package buyer
+
+import (
+	"errors"
+
+	"github.com/coinbase/x402/go/pkg/types"
+
+	"github.com/selesy/x402-buyer/internal/exact/svm"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+type payerKey struct {
+	scheme  api.Scheme
+	network string
+}
+
+// registry routes types.PaymentRequirements to the api.Payer registered for
+// its scheme and network.
+type registry struct {
+	networks  *api.Networks
+	byNetwork map[payerKey]api.Payer
+	byScheme  map[api.Scheme][]api.Payer
+}
+
+func newRegistry(networks *api.Networks) *registry {
+	return &registry{
+		networks:  networks,
+		byNetwork: map[payerKey]api.Payer{},
+		byScheme:  map[api.Scheme][]api.Payer{},
+	}
+}
+
+// key returns the payerKey for the scheme and network.  Known networks are
+// identified by their CAIP-2 identifier so that a payer registered using a
+// network's x402 version 1 name is also found for version 2 requirements.
+func (r *registry) key(scheme api.Scheme, network string) payerKey {
+	if known, err := r.networks.Lookup(network); err == nil {
+		network = known.CAIP2()
+	} else if known, ok := svm.LookupNetwork(network); ok {
+		network = known.CAIP2
+	}
+
+	return payerKey{scheme: scheme, network: network}
+}
+
+// register adds the payer to the registry for each of the provided networks.
+// If no networks are provided, the payer is consulted for any network within
+// its scheme after payers registered for specific networks.
+func (r *registry) register(payer api.Payer, networks ...string) {
+	if len(networks) == 0 {
+		r.byScheme[payer.Scheme()] = append(r.byScheme[payer.Scheme()], payer)
+
+		return
+	}
+
+	for _, network := range networks {
+		r.byNetwork[r.key(payer.Scheme(), network)] = payer
+	}
+}
+
+// registerSupported registers a payer for any network within its scheme
+// using the results of its constructor.  Payers whose constructor returns
+// api.ErrUnsupportedSigner are skipped but any other error is returned.
+func (r *registry) registerSupported(payer api.Payer, err error) error {
+	if errors.Is(err, api.ErrUnsupportedSigner) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.register(payer)
+
+	return nil
+}
+
+// lookup returns the api.Payer that should make the payment described by
+// the provided requirements.
+func (r *registry) lookup(requirements types.PaymentRequirements) (api.Payer, bool) {
+	scheme := api.Scheme(requirements.Scheme)
+
+	if payer, ok := r.byNetwork[r.key(scheme, requirements.Network)]; ok {
+		return payer, true
+	}
+
+	for _, payer := range r.byScheme[scheme] {
+		if np, ok := payer.(api.NetworkPayer); ok && !np.Supports(requirements.Network) {
+			continue
+		}
+
+		return payer, true
+	}
+
+	return nil, false
+}
+
+// payable returns the requirements from accepts that a registered api.Payer
+// is able to pay.  Requirements that fail the api.Validator checks of their
+// payer are treated as unpayable and the returned error describes why.
+func (r *registry) payable(accepts []types.PaymentRequirements) ([]types.PaymentRequirements, error) {
+	var errs error
+
+	out := make([]types.PaymentRequirements, 0, len(accepts))
+
+	for _, requirements := range accepts {
+		payer, ok := r.lookup(requirements)
+		if !ok {
+			continue
+		}
+
+		if v, ok := payer.(api.Validator); ok {
+			if err := v.Validate(requirements); err != nil {
+				errs = errors.Join(errs, err)
+
+				continue
+			}
+		}
+
+		out = append(out, requirements)
+	}
+
+	return out, errs
+}
@@ -0,0 +1,105 @@
+package buyer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/lmittmann/tint"
+
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// ErrPaymentRejected is returned (wrapped in a PaymentRejectedError) when
+// the seller responds to a request carrying a payment with another 402
+// Payment Required response.
+var ErrPaymentRejected = errors.New("payment rejected")
+
+// DefaultFallbackReasons are the rejection reasons that, when the
+// WithFallback Option is provided without reasons, suggest that a payment
+// using a different network or asset might be accepted.
+var DefaultFallbackReasons = []string{
+	"insufficient_funds",
+	"invalid_network",
+	"invalid_scheme",
+	"unsupported_scheme",
+}
+
+// PaymentRejectedError describes a payment that was made but rejected by
+// the seller (or its facilitator.)
+type PaymentRejectedError struct {
+	// Reason is the value of the error field of the seller's 402 Payment
+	// Required response.
+	Reason string
+	// Requirements are the payment requirements that were paid.
+	Requirements types.PaymentRequirements
+	// Payload is the signed payment that was rejected, whatever its
+	// scheme, network and transfer method.
+	Payload *api.PaymentPayload
+}
+
+// Error implements error.
+func (e *PaymentRejectedError) Error() string {
+	if e.Reason == "" {
+		return ErrPaymentRejected.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrPaymentRejected, e.Reason)
+}
+
+// Is allows the PaymentRejectedError to match ErrPaymentRejected when
+// using errors.Is.
+func (e *PaymentRejectedError) Is(target error) bool {
+	return target == ErrPaymentRejected
+}
+
+// rejection reads the reason for the rejection of a payment from the
+// seller's 402 Payment Required response and closes its body.
+func (t *Transport) rejection(resp *http.Response, requirements types.PaymentRequirements, payload *api.PaymentPayload) *PaymentRejectedError {
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.log.Error("failed to close response body", tint.Err(err))
+		}
+	}()
+
+	rejection := &PaymentRejectedError{
+		Requirements: requirements,
+		Payload:      payload,
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.log.Warn("failed to read rejected payment response", tint.Err(err))
+
+		return rejection
+	}
+
+	paymentRequest, err := parsePaymentRequired(resp.Header, body)
+	if err != nil {
+		t.log.Warn("failed to unmarshal rejected payment response", tint.Err(err))
+
+		return rejection
+	}
+
+	rejection.Reason = paymentRequest.err
+
+	t.log.Warn(
+		"x402 payment rejected",
+		slog.String("reason", rejection.Reason),
+		slog.String("scheme", requirements.Scheme),
+		slog.String("network", requirements.Network),
+	)
+
+	return rejection
+}
+
+// fallsBack returns true if the Transport should attempt a payment with a
+// different network or asset after the provided rejection.
+func (c *config) fallsBack(rejection *PaymentRejectedError) bool {
+	_, ok := c.fallbackReasons[rejection.Reason]
+
+	return ok
+}
@@ -0,0 +1,130 @@
+package buyer
+
+import (
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/coinbase/x402/go/pkg/types"
+)
+
+// A Selector ranks the types.PaymentRequirements offered in the accepts
+// field of a 402 Payment Required response.
+//
+// Select returns the acceptable requirements in order of preference, with
+// the most preferred first.  Requirements that should never be paid can be
+// omitted from the result.  The returned slice should contain elements of
+// the provided slice and may share its backing array.
+//
+// The Transport only offers a Selector the requirements it is able to pay
+// so the result of SelectFirst is the first requirement that the Transport
+// has a payer for.
+type Selector interface {
+	Select(accepts []types.PaymentRequirements) []types.PaymentRequirements
+}
+
+// SelectorFunc is an adapter that allows the use of an ordinary function
+// as a Selector.
+type SelectorFunc func(accepts []types.PaymentRequirements) []types.PaymentRequirements
+
+// Select implements Selector.
+func (f SelectorFunc) Select(accepts []types.PaymentRequirements) []types.PaymentRequirements {
+	return f(accepts)
+}
+
+// SelectFirst returns a Selector that preserves the order chosen by the
+// seller.  This is the default Selector.
+func SelectFirst() Selector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) []types.PaymentRequirements {
+		return accepts
+	})
+}
+
+// SelectCheapest returns a Selector that ranks requirements by ascending
+// MaxAmountRequired.  Requirements with equal amounts retain the seller's
+// order while requirements whose amount can't be parsed are discarded.
+//
+// Amounts are compared in the atomic units of each asset, so this Selector
+// is most useful in combination with SelectAssets or when all the assets
+// offered share the same number of decimals.
+func SelectCheapest() Selector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) []types.PaymentRequirements {
+		type ranked struct {
+			amount       *big.Int
+			requirements types.PaymentRequirements
+		}
+
+		rs := make([]ranked, 0, len(accepts))
+
+		for _, requirements := range accepts {
+			amount, ok := new(big.Int).SetString(requirements.MaxAmountRequired, 10)
+			if !ok {
+				continue
+			}
+
+			rs = append(rs, ranked{amount: amount, requirements: requirements})
+		}
+
+		slices.SortStableFunc(rs, func(a, b ranked) int {
+			return a.amount.Cmp(b.amount)
+		})
+
+		out := make([]types.PaymentRequirements, 0, len(rs))
+		for _, r := range rs {
+			out = append(out, r.requirements)
+		}
+
+		return out
+	})
+}
+
+// SelectNetworks returns a Selector that ranks requirements according to
+// the order of the provided networks.  Requirements for networks that are
+// not listed are discarded.
+func SelectNetworks(networks ...string) Selector {
+	return selectByPreference(networks, func(requirements types.PaymentRequirements) string {
+		return requirements.Network
+	})
+}
+
+// SelectAssets returns a Selector that ranks requirements according to the
+// order of the provided asset addresses.  Addresses are compared without
+// regard to case and requirements for assets that are not listed are
+// discarded.
+func SelectAssets(assets ...string) Selector {
+	return selectByPreference(assets, func(requirements types.PaymentRequirements) string {
+		return requirements.Asset
+	})
+}
+
+// SelectChain returns a Selector that applies each of the provided
+// selectors in turn, passing the result of one as the input of the next.
+//
+// Since the built-in selectors retain the relative order of requirements
+// they consider equal, the last Selector provided determines the primary
+// ordering.
+func SelectChain(selectors ...Selector) Selector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) []types.PaymentRequirements {
+		for _, selector := range selectors {
+			accepts = selector.Select(accepts)
+		}
+
+		return accepts
+	})
+}
+
+func selectByPreference(prefs []string, key func(types.PaymentRequirements) string) Selector {
+	return SelectorFunc(func(accepts []types.PaymentRequirements) []types.PaymentRequirements {
+		out := make([]types.PaymentRequirements, 0, len(accepts))
+
+		for _, pref := range prefs {
+			for _, requirements := range accepts {
+				if strings.EqualFold(pref, key(requirements)) {
+					out = append(out, requirements)
+				}
+			}
+		}
+
+		return out
+	})
+}
@@ -0,0 +1,87 @@
+package buyer_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestSelector(t *testing.T) {
+	t.Parallel()
+
+	accepts := []types.PaymentRequirements{
+		{Network: "base", Asset: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", MaxAmountRequired: "30000"},
+		{Network: "base-sepolia", Asset: "0x036CbD53842c5426634e7929541eC2318f3dCF7e", MaxAmountRequired: "10000"},
+		{Network: "base", Asset: "0x0000000000000000000000000000000000000001", MaxAmountRequired: "20000"},
+		{Network: "base", Asset: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", MaxAmountRequired: "invalid"},
+	}
+
+	amounts := func(accepts []types.PaymentRequirements) []string {
+		out := []string{}
+		for _, requirements := range accepts {
+			out = append(out, requirements.MaxAmountRequired)
+		}
+
+		return out
+	}
+
+	for _, tc := range []struct {
+		name     string
+		selector buyer.Selector
+		exp      []string
+	}{
+		{
+			name:     "first",
+			selector: buyer.SelectFirst(),
+			exp:      []string{"30000", "10000", "20000", "invalid"},
+		},
+		{
+			name:     "cheapest",
+			selector: buyer.SelectCheapest(),
+			exp:      []string{"10000", "20000", "30000"},
+		},
+		{
+			name:     "networks",
+			selector: buyer.SelectNetworks("base-sepolia", "base"),
+			exp:      []string{"10000", "30000", "20000", "invalid"},
+		},
+		{
+			name:     "unknown network",
+			selector: buyer.SelectNetworks("polygon"),
+			exp:      []string{},
+		},
+		{
+			name:     "assets",
+			selector: buyer.SelectAssets("0x833589FCD6EDB6E08F4C7C32D4F71B54BDA02913"),
+			exp:      []string{"30000", "invalid"},
+		},
+		{
+			name:     "chain",
+			selector: buyer.SelectChain(buyer.SelectCheapest(), buyer.SelectNetworks("base")),
+			exp:      []string{"20000", "30000"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.exp, amounts(tc.selector.Select(accepts)))
+		})
+	}
+}
+
+func TestWithSelector(t *testing.T) {
+	t.Parallel()
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	_, err = buyer.NewTransport(http.DefaultTransport, signer, buyer.WithSelector(nil))
+	require.Error(t, err)
+}
@@ -0,0 +1,292 @@
+package buyer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
+)
+
+// ErrUnknownSignerScheme is returned by SignerFromURI when no
+// SignerResolver is registered for the URI's scheme.
+var ErrUnknownSignerScheme = errors.New("unknown signer scheme")
+
+// ErrInvalidSignerURI is returned by SignerFromURI when a signer URI is
+// malformed or is missing required parameters.
+var ErrInvalidSignerURI = errors.New("invalid signer URI")
+
+// A SignerResolver creates the api.Signer described by a signer URI.
+type SignerResolver func(ctx context.Context, uri *url.URL) (api.Signer, error)
+
+var signerSchemes = struct {
+	sync.RWMutex
+	resolvers map[string]SignerResolver
+}{
+	resolvers: map[string]SignerResolver{
+		"env":      resolveEnvSigner,
+		"hex":      resolveHexSigner,
+		"file":     resolveFileSigner,
+		"keystore": resolveKeyStoreSigner,
+		"external": resolveExternalSigner,
+	},
+}
+
+// RegisterSignerScheme makes a SignerResolver available to SignerFromURI
+// for URIs with the scheme.  It's intended to be called from the init
+// function of packages that provide additional key sources.  If
+// RegisterSignerScheme is called twice with the same scheme or if resolver
+// is nil, it panics.
+func RegisterSignerScheme(scheme string, resolver SignerResolver) {
+	signerSchemes.Lock()
+	defer signerSchemes.Unlock()
+
+	scheme = strings.ToLower(scheme)
+
+	if resolver == nil {
+		panic("buyer: RegisterSignerScheme resolver is nil")
+	}
+
+	if _, ok := signerSchemes.resolvers[scheme]; ok {
+		panic("buyer: RegisterSignerScheme called twice for scheme " + scheme)
+	}
+
+	signerSchemes.resolvers[scheme] = resolver
+}
+
+// SignerFromURI returns the api.Signer described by the URI, which allows
+// the key source to be chosen using configuration.  The following schemes
+// are supported by default:
+//
+//   - env:NAME reads a hexadecimal private key from the environment
+//     variable NAME.
+//   - hex:KEY uses the hexadecimal private key KEY.
+//   - file:/path reads a hexadecimal private key from the file at path.
+//   - keystore:/dir?address=0x...&password-file=/path uses the account
+//     with the address in the go-ethereum keystore in dir.  The passphrase
+//     is read from the password-file (or the environment variable named by
+//     password-env.)  The address may be omitted if the keystore contains a
+//     single account.
+//   - external:ENDPOINT?address=0x... uses the account with the address
+//     managed by the Clef-compatible external signer at ENDPOINT (e.g.
+//     external:unix:///path/clef.ipc or external:http://localhost:8550.)
+//     If the address is omitted, the external signer's first account is
+//     used.
+//
+// Additional schemes can be added using RegisterSignerScheme.
+func SignerFromURI(ctx context.Context, uri string) (api.Signer, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		// The parse error includes the URI, which might contain a private
+		// key, so only the scheme is reported.
+		scheme, _, _ := strings.Cut(uri, ":")
+		if !isScheme(scheme) {
+			scheme = ""
+		}
+
+		return nil, fmt.Errorf("%w: malformed %q URI", ErrInvalidSignerURI, scheme)
+	}
+
+	signerSchemes.RLock()
+	resolver, ok := signerSchemes.resolvers[strings.ToLower(u.Scheme)]
+	signerSchemes.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSignerScheme, u.Scheme)
+	}
+
+	return resolver(ctx, u)
+}
+
+// ClientForSignerURI returns an http.Client capable of making x402
+// payments using the api.Signer described by the URI (see SignerFromURI.)
+// The context bounds the creation of the api.Signer (e.g. connecting to an
+// external signer) but not the payments that are later made.
+func ClientForSignerURI(ctx context.Context, uri string, opts ...Option) (*http.Client, error) {
+	signer, err := SignerFromURI(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return ClientForSigner(signer, opts...)
+}
+
+// isScheme returns true if s is a valid URI scheme (RFC 3986 section 3.1).
+func isScheme(s string) bool {
+	for i, c := range s {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+// opaque returns the part of the URI after its scheme (excluding any query)
+// whether or not it begins with a slash.
+func opaque(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+
+	return u.Path
+}
+
+func resolveEnvSigner(_ context.Context, u *url.URL) (api.Signer, error) {
+	name := opaque(u)
+	if name == "" {
+		return nil, fmt.Errorf("%w: environment variable name is required", ErrInvalidSignerURI)
+	}
+
+	return signer.NewECDSASignerFromEnv(name)
+}
+
+func resolveHexSigner(_ context.Context, u *url.URL) (api.Signer, error) {
+	return signer.NewECDSASignerFromHex(strings.TrimPrefix(opaque(u), "0x"))
+}
+
+func resolveFileSigner(_ context.Context, u *url.URL) (api.Signer, error) {
+	data, err := os.ReadFile(opaque(u))
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.NewECDSASignerFromHex(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+}
+
+func resolveKeyStoreSigner(_ context.Context, u *url.URL) (api.Signer, error) {
+	dir := opaque(u)
+	if dir == "" {
+		return nil, fmt.Errorf("%w: keystore directory is required", ErrInvalidSignerURI)
+	}
+
+	query := u.Query()
+
+	address := query.Get("address")
+	if address != "" && !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("%w: %q is not an address", ErrInvalidSignerURI, address)
+	}
+
+	var pass string
+
+	switch {
+	case query.Has("password-file"):
+		data, err := os.ReadFile(query.Get("password-file"))
+		if err != nil {
+			return nil, err
+		}
+
+		pass = strings.TrimRight(string(data), "\r\n")
+	case query.Has("password-env"):
+		var ok bool
+
+		if pass, ok = os.LookupEnv(query.Get("password-env")); !ok {
+			return nil, fmt.Errorf("%w: %s", signer.ErrEnvVarNotFound, query.Get("password-env"))
+		}
+	default:
+		return nil, fmt.Errorf("%w: password-file or password-env is required", ErrInvalidSignerURI)
+	}
+
+	data, err := readKeyFile(dir, address)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keystore.DecryptKey(data, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.NewECDSASigner(key.PrivateKey)
+}
+
+// readKeyFile returns the contents of the key file in the keystore dir for
+// the address or, if address is empty, of the keystore's only key file.
+// Unlike a keystore.KeyStore, no watcher is started for the directory.
+func readKeyFile(dir, address string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found [][]byte
+
+	for _, entry := range entries {
+		// Skip directories and the editor backups and hidden files
+		// ignored by go-ethereum's keystore.
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		var key struct {
+			Address string `json:"address"`
+		}
+
+		if err := json.Unmarshal(data, &key); err != nil || !common.IsHexAddress(key.Address) {
+			continue
+		}
+
+		if address == "" || common.HexToAddress(key.Address) == common.HexToAddress(address) {
+			found = append(found, data)
+		}
+	}
+
+	switch {
+	case address != "" && len(found) == 0:
+		return nil, fmt.Errorf("%w: %s", signer.ErrAccountNotFound, common.HexToAddress(address).Hex())
+	case len(found) != 1:
+		return nil, fmt.Errorf("%w: address is required when the keystore doesn't contain exactly one account", ErrInvalidSignerURI)
+	}
+
+	return found[0], nil
+}
+
+func resolveExternalSigner(ctx context.Context, u *url.URL) (api.Signer, error) {
+	endpoint := opaque(u)
+	if endpoint == "" {
+		return nil, fmt.Errorf("%w: external signer endpoint is required", ErrInvalidSignerURI)
+	}
+
+	// The rest of the query belongs to the endpoint.
+	query := u.Query()
+	rawQuery := u.RawQuery
+
+	var address common.Address
+
+	if query.Has("address") {
+		if !common.IsHexAddress(query.Get("address")) {
+			return nil, fmt.Errorf("%w: %q is not an address", ErrInvalidSignerURI, query.Get("address"))
+		}
+
+		address = common.HexToAddress(query.Get("address"))
+
+		query.Del("address")
+		rawQuery = query.Encode()
+	}
+
+	if rawQuery != "" {
+		endpoint += "?" + rawQuery
+	}
+
+	return signer.NewExternalSigner(ctx, endpoint, address)
+}
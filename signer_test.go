@@ -0,0 +1,133 @@
+package buyer_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestSignerFromURI(t *testing.T) {
+	t.Setenv(apitest.ECDSAPrivateKeyHexEnvVarName, apitest.ECDSAPrivateKeyHex)
+
+	dir := t.TempDir()
+
+	keyFile := filepath.Join(dir, "key.hex")
+	require.NoError(t, os.WriteFile(keyFile, []byte("0x"+apitest.ECDSAPrivateKeyHex+"\n"), 0o600))
+
+	passFile := filepath.Join(dir, "password.txt")
+	require.NoError(t, os.WriteFile(passFile, []byte(apitest.Passphrase+"\n"), 0o600))
+
+	_, acct := apitest.Keystore(t)
+	external := apitest.NewExternalSigner(t)
+	address := crypto.PubkeyToAddress(apitest.PrivateKey(t).PublicKey)
+
+	for _, tc := range []struct {
+		name string
+		uri  string
+	}{
+		{name: "env", uri: "env:" + apitest.ECDSAPrivateKeyHexEnvVarName},
+		{name: "hex", uri: "hex:" + apitest.ECDSAPrivateKeyHex},
+		{name: "hex with prefix", uri: "hex:0x" + apitest.ECDSAPrivateKeyHex},
+		{name: "file", uri: "file:" + keyFile},
+		{name: "keystore", uri: "keystore:" + filepath.Dir(acct.URL.Path) + "?address=" + acct.Address.Hex() + "&password-file=" + url.QueryEscape(passFile)},
+		{name: "keystore without address", uri: "keystore:" + filepath.Dir(acct.URL.Path) + "?password-file=" + url.QueryEscape(passFile)},
+		{name: "external", uri: "external:" + external.URL},
+		{name: "external with address", uri: "external:" + external.URL + "?address=" + address.Hex()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := buyer.SignerFromURI(t.Context(), tc.uri)
+			require.NoError(t, err)
+
+			evmSigner, ok := s.(api.EVMSigner)
+			require.True(t, ok)
+			assert.Equal(t, address, evmSigner.Address())
+		})
+	}
+}
+
+func TestSignerFromURIErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		uri  string
+		err  error
+	}{
+		{name: "unknown scheme", uri: "vault:secret/x402", err: buyer.ErrUnknownSignerScheme},
+		{name: "missing scheme", uri: apitest.ECDSAPrivateKeyHex, err: buyer.ErrUnknownSignerScheme},
+		{name: "missing env var", uri: "env:X402_BUYER_TEST_MISSING_KEY", err: signer.ErrEnvVarNotFound},
+		{name: "missing env var name", uri: "env:", err: buyer.ErrInvalidSignerURI},
+		{name: "missing keystore password", uri: "keystore:/tmp/keystore", err: buyer.ErrInvalidSignerURI},
+		{name: "invalid keystore address", uri: "keystore:/tmp/keystore?address=0x1234&password-env=X", err: buyer.ErrInvalidSignerURI},
+		{name: "invalid external address", uri: "external:http://localhost:8550?address=0x1234", err: buyer.ErrInvalidSignerURI},
+		{name: "malformed hex key", uri: "hex:" + apitest.ECDSAPrivateKeyHex + "\n", err: buyer.ErrInvalidSignerURI},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := buyer.SignerFromURI(t.Context(), tc.uri)
+			require.ErrorIs(t, err, tc.err)
+			assert.NotContains(t, err.Error(), apitest.ECDSAPrivateKeyHex)
+		})
+	}
+
+	t.Run("keystore account not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, acct := apitest.Keystore(t)
+
+		passFile := filepath.Join(t.TempDir(), "password.txt")
+		require.NoError(t, os.WriteFile(passFile, []byte(apitest.Passphrase), 0o600))
+
+		_, err := buyer.SignerFromURI(t.Context(), "keystore:"+filepath.Dir(acct.URL.Path)+"?address=0x60ac86571E55F9735F00cE9e28361d203977B260&password-file="+url.QueryEscape(passFile))
+		require.ErrorIs(t, err, signer.ErrAccountNotFound)
+	})
+
+	t.Run("external account not found", func(t *testing.T) {
+		t.Parallel()
+
+		external := apitest.NewExternalSigner(t)
+
+		_, err := buyer.SignerFromURI(t.Context(), "external:"+external.URL+"?address=0x60ac86571E55F9735F00cE9e28361d203977B260")
+		require.ErrorIs(t, err, signer.ErrExternalAccountNotFound)
+	})
+}
+
+func TestRegisterSignerScheme(t *testing.T) {
+	t.Parallel()
+
+	var resolved *url.URL
+
+	buyer.RegisterSignerScheme("test-vault", func(_ context.Context, u *url.URL) (api.Signer, error) {
+		resolved = u
+
+		return signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	})
+
+	s, err := buyer.SignerFromURI(t.Context(), "test-vault://secrets/x402?version=2")
+	require.NoError(t, err)
+	assert.NotNil(t, s)
+	require.NotNil(t, resolved)
+	assert.Equal(t, "secrets", resolved.Host)
+	assert.Equal(t, "2", resolved.Query().Get("version"))
+
+	cl, err := buyer.ClientForSignerURI(t.Context(), "test-vault://secrets/x402")
+	require.NoError(t, err)
+	assert.NotNil(t, cl)
+
+	assert.Panics(t, func() {
+		buyer.RegisterSignerScheme("TEST-VAULT", func(context.Context, *url.URL) (api.Signer, error) { return nil, nil })
+	})
+	assert.Panics(t, func() { buyer.RegisterSignerScheme("test-nil", nil) })
+}
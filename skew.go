@@ -0,0 +1,73 @@
+package buyer
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSkew is the largest difference between the local clock and a seller's
+// clock that's compensated for.  Larger differences are more likely to be
+// a misconfigured (or malicious) seller than a drifting local clock.
+const maxSkew = 5 * time.Minute
+
+// skew estimates the difference between the local clock and the clocks of
+// sellers (and by extension their facilitators) using the Date header of
+// their responses.  Each seller's host has its own estimate so that one
+// seller can't shift the validity windows of payments to others.
+//
+// A nil *skew is valid and never compensates for any difference.
+type skew struct {
+	mu      sync.RWMutex
+	local   func() time.Time
+	offsets map[string]time.Duration
+}
+
+func newSkew() *skew {
+	return &skew{
+		local:   time.Now,
+		offsets: map[string]time.Duration{},
+	}
+}
+
+// observe updates the estimated offset of the host using the Date header
+// of its response.  Since the Date header only has a resolution of one
+// second, smaller differences are ignored.  Differences larger than
+// maxSkew are also ignored.
+func (s *skew) observe(host string, header http.Header) {
+	if s == nil {
+		return
+	}
+
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	offset := date.Add(500 * time.Millisecond).Sub(s.local())
+	if offset.Abs() > maxSkew {
+		return
+	}
+
+	if offset.Abs() < time.Second {
+		offset = 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offsets[strings.ToLower(host)] = offset
+}
+
+// offset returns the last observed offset of the host's clock.
+func (s *skew) offset(host string) time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.offsets[strings.ToLower(host)]
+}
@@ -0,0 +1,136 @@
+package buyer_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	buyer "github.com/selesy/x402-buyer"
+	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api/apitest"
+)
+
+func TestValidityWindow(t *testing.T) {
+	t.Parallel()
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	// validity returns the validity window of the payment's authorization.
+	validity := func(t *testing.T, resp *http.Response) (int64, int64) {
+		t.Helper()
+
+		payment, ok := buyer.PaymentFromResponse(resp)
+		require.True(t, ok)
+
+		payload, ok := payment.Payload.ExactEvm()
+		require.True(t, ok)
+
+		authorization := payload.Authorization
+
+		validAfter, err := strconv.ParseInt(authorization.ValidAfter, 10, 64)
+		require.NoError(t, err)
+
+		validBefore, err := strconv.ParseInt(authorization.ValidBefore, 10, 64)
+		require.NoError(t, err)
+
+		return validAfter, validBefore
+	}
+
+	// dated returns a 402 Payment Required response from a seller whose
+	// clock reads date.
+	dated := func(date time.Time) *http.Response {
+		resp := paymentRequiredResponse(payReq)
+		resp.Header = http.Header{
+			"Date": []string{date.UTC().Format(http.TimeFormat)},
+		}
+
+		return resp
+	}
+
+	// window pays for a request and returns the validity window of the
+	// payment's authorization.
+	window := func(t *testing.T, date time.Time, opts ...buyer.Option) (int64, int64) {
+		t.Helper()
+
+		trans, _ := newTestTransport(t, []*http.Response{dated(date), okResponse()}, opts...)
+
+		resp, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		return validity(t, resp)
+	}
+
+	t.Run("passes - default window", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+
+		validAfter, validBefore := window(t, now.Add(time.Hour))
+		assert.InDelta(t, now.Add(-10*time.Minute).Unix(), validAfter, 2)
+		assert.InDelta(t, now.Add(time.Minute).Unix(), validBefore, 2)
+	})
+
+	t.Run("passes - configured window", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+
+		validAfter, validBefore := window(t, now, buyer.WithValidityWindow(time.Minute, 30*time.Second))
+		assert.InDelta(t, now.Add(-time.Minute).Unix(), validAfter, 2)
+		assert.InDelta(t, now.Add(30*time.Second).Unix(), validBefore, 2)
+	})
+
+	t.Run("passes - clock skew compensation", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		sellerNow := now.Add(3 * time.Minute)
+
+		validAfter, validBefore := window(t, sellerNow, buyer.WithClockSkewCompensation())
+		assert.InDelta(t, sellerNow.Add(-10*time.Minute).Unix(), validAfter, 2)
+		assert.InDelta(t, sellerNow.Add(time.Minute).Unix(), validBefore, 2)
+	})
+
+	t.Run("passes - clock skew beyond maximum ignored", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+
+		validAfter, validBefore := window(t, now.Add(time.Hour), buyer.WithClockSkewCompensation())
+		assert.InDelta(t, now.Add(-10*time.Minute).Unix(), validAfter, 2)
+		assert.InDelta(t, now.Add(time.Minute).Unix(), validBefore, 2)
+	})
+
+	t.Run("passes - clock skew compensated per host", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+
+		trans, _ := newTestTransport(t, []*http.Response{dated(now.Add(3 * time.Minute)), okResponse(), paymentRequiredResponse(payReq), okResponse()}, buyer.WithClockSkewCompensation())
+
+		resp, err := doRequest(t, trans, "https://example.com")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		resp, err = doRequest(t, trans, "https://example.org")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		validAfter, validBefore := validity(t, resp)
+		assert.InDelta(t, now.Add(-10*time.Minute).Unix(), validAfter, 2)
+		assert.InDelta(t, now.Add(time.Minute).Unix(), validBefore, 2)
+	})
+
+	t.Run("fails - negative window", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := buyer.NewTransport(http.DefaultTransport, signer, buyer.WithValidityWindow(-time.Minute, 0))
+		require.Error(t, err)
+	})
+}
@@ -1,24 +1,33 @@
 package buyer
 
 import (
-	"bytes"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/big"
 	"net/http"
-	"time"
+	"slices"
+	"strings"
 
 	"github.com/coinbase/x402/go/pkg/types"
 	"github.com/lmittmann/tint"
 
 	"github.com/selesy/x402-buyer/internal/exact/evm"
+	"github.com/selesy/x402-buyer/internal/exact/svm"
+	"github.com/selesy/x402-buyer/internal/nonce"
+	uptoevm "github.com/selesy/x402-buyer/internal/upto/evm"
 	"github.com/selesy/x402-buyer/pkg/api"
 )
 
 var _ http.RoundTripper = (*Transport)(nil)
 
+// ErrNoAcceptablePayment is returned when none of the payment requirements
+// offered by the seller can be paid by the Transport or are acceptable to
+// its Selector.
+var ErrNoAcceptablePayment = errors.New("no acceptable payment requirements")
+
 // Transport is an http.RoundTripper that is capable of making x402 payments
 // to access HTTP-based content or services on the Internet.
 type Transport struct {
@@ -37,114 +46,325 @@ func NewTransport(next http.RoundTripper, signer api.Signer, opts ...Option) (*T
 		return nil, err
 	}
 
-	return newTransport(next, signer, cfg), nil
+	transport, err := newTransport(next, signer, cfg)
+	if err != nil {
+		cfg.close()
+
+		return nil, err
+	}
+
+	return transport, nil
 }
 
-func newTransport(next http.RoundTripper, signer api.Signer, cfg *config) *Transport {
+func newTransport(next http.RoundTripper, signer api.Signer, cfg *config) (*Transport, error) {
+	var opts []api.Option
+
+	if len(cfg.callers) > 0 {
+		opts = append(opts, api.WithPermitNonceFunc(cfg.permitNonce))
+	}
+
+	if cfg.nonceStore != nil || len(cfg.callers) > 0 {
+		var nonceOpts []nonce.Option
+
+		if cfg.nonceSeed != nil {
+			nonceOpts = append(nonceOpts, nonce.WithSeed(cfg.nonceSeed))
+		}
+
+		if len(cfg.callers) > 0 {
+			nonceOpts = append(nonceOpts, nonce.WithChecker(cfg.nonceUsed))
+		}
+
+		opts = append(opts, api.WithNonceSource(nonce.NewSource(cfg.nonceStore, nonceOpts...)))
+	}
+
+	opts = append(opts, cfg.payerOpts...)
+
+	// The built-in payers are only registered for signers they can use.
+	if err := cfg.payers.registerSupported(evm.NewExactEvm(signer, cfg.networks, cfg.tokens, cfg.log, opts...)); err != nil {
+		return nil, err
+	}
+
+	if cfg.upto != nil {
+		if err := cfg.payers.registerSupported(uptoevm.NewUptoEvm(signer, cfg.networks, cfg.tokens, cfg.upto, cfg.log, opts...)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Solana RPC requests bypass the payment middleware but otherwise use
+	// the configured client.
+	rpcClient := *cfg.client
+	rpcClient.Transport = next
+
+	if err := cfg.payers.registerSupported(svm.NewExactSvm(signer, cfg.solanaRPC, cfg.mints, &rpcClient, cfg.log, opts...)); err != nil {
+		return nil, err
+	}
+
 	return &Transport{
 		config: *cfg,
 
 		next:   next,
 		signer: signer,
-	}
+	}, nil
+}
+
+// Close releases the connections to the JSON-RPC endpoints provided using
+// WithRPC.  Payments on those networks that need to call the endpoint
+// (e.g. to check a nonce) fail once the Transport is closed.
+func (t *Transport) Close() error {
+	t.close()
+
+	return nil
 }
 
 // RoundTrip implements http.RoundTripper.
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Body can only be read one time ... since we make two round-trips
-	// if a payment is required, we have to duplicate the body.  So we
-	// read the bytes and will create new readers for each call.
+	// if a payment is required, we have to be able to produce the body
+	// a second time.  See newReplayableBody for how that's done without
+	// holding large bodies in memory.
+	body, err := t.newReplayableBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
 
-	var (
-		body []byte
-		err  error
-	)
+	defer body.close(t.log)
 
-	if req.Body != nil {
-		defer func() {
-			if err := req.Body.Close(); err != nil {
-				t.log.Error("failed to close request body", tint.Err(err))
-			}
-		}()
+	var resp *http.Response
 
-		body, err = io.ReadAll(req.Body)
+	if candidate, ok := t.cache.get(req); ok {
+		// Pay using the requirements from the last 402 Payment Required
+		// response for this resource without waiting to be asked.
+		paidResp, payment, release, err := t.pay(req, body, candidate)
 		if err != nil {
 			return nil, err
 		}
 
-	}
+		if paidResp.StatusCode != http.StatusPaymentRequired {
+			return t.paid(req, paidResp, candidate.requirements, payment), nil
+		}
 
-	// Perform the http.Request
-	if req.Body != nil {
-		req.Body = io.NopCloser(bytes.NewReader(body))
-	}
+		t.log.Debug("Preemptive payment not accepted", slog.String("url", req.URL.String()))
 
-	resp, err := t.next.RoundTrip(req)
-	if err != nil {
-		return nil, err
-	}
+		release()
+		t.cache.invalidate(req)
+
+		resp = paidResp
+	} else {
+		// Perform the http.Request
+		if req.Body, err = body.next(); err != nil {
+			return nil, err
+		}
+
+		resp, err = t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
 
-	// Return the http.Response if no payment is required
-	if resp.StatusCode != http.StatusPaymentRequired {
-		return resp, nil
+		// Return the http.Response if no payment is required
+		if resp.StatusCode != http.StatusPaymentRequired {
+			return resp, nil
+		}
 	}
 
-	// Intercept the response with a copy of the request
-	if req.Body != nil {
-		req.Body = io.NopCloser(bytes.NewReader(body))
+	// A payment can't be made if the request can't be sent again
+	if !body.replayable() {
+		return nil, errors.Join(ErrBodyNotReplayable, resp.Body.Close())
 	}
 
-	return t.handlePaymentRequired(req, resp)
+	return t.handlePaymentRequired(req, body, resp)
 }
 
-func (t *Transport) handlePaymentRequired(req *http.Request, resp *http.Response) (*http.Response, error) {
+func (t *Transport) handlePaymentRequired(req *http.Request, body *replayableBody, resp *http.Response) (*http.Response, error) {
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			t.log.Error("failed to close response body", tint.Err(err))
 		}
 	}()
 
-	body, err := io.ReadAll(resp.Body)
+	t.skew.observe(req.URL.Hostname(), resp.Header)
+
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	t.log.Debug("Payment request body", slog.String("json", string(body)))
+	t.log.Debug("Payment request body", slog.String("json", string(respBody)))
 
-	var paymentRequest api.PaymentRequest
-	if err := json.Unmarshal(body, &paymentRequest); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payment request: %w", err)
+	paymentRequest, err := parsePaymentRequired(resp.Header, respBody)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(paymentRequest.Accepts) == 0 {
+	t.log.Debug("Payment request version", slog.Int("version", paymentRequest.version))
+
+	if len(paymentRequest.accepts) == 0 {
 		return nil, fmt.Errorf("no payment methods accepted")
 	}
 
-	// TODO: For simplicity, we'll just use the first accepted payment method.
-	paymentDetails := paymentRequest.Accepts[0]
+	payable, invalid := t.payers.payable(paymentRequest.accepts)
+	if invalid != nil {
+		t.log.Warn("invalid payment requirements ignored", tint.Err(invalid))
+	}
 
-	payment, err := t.createPayment(paymentDetails)
+	candidates := paymentRequest.offers(t.selector.Select(payable))
+	if len(candidates) == 0 {
+		if invalid != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNoAcceptablePayment, invalid)
+		}
+
+		return nil, ErrNoAcceptablePayment
+	}
+
+	var (
+		rejection *PaymentRejectedError
+		tried     []types.PaymentRequirements
+	)
+
+	for _, candidate := range candidates {
+		paymentDetails := candidate.requirements
+
+		if rejection != nil {
+			if !t.fallsBack(rejection) {
+				break
+			}
+
+			if slices.ContainsFunc(tried, func(r types.PaymentRequirements) bool {
+				return r.Network == paymentDetails.Network && strings.EqualFold(r.Asset, paymentDetails.Asset)
+			}) {
+				continue
+			}
+
+			t.log.Info(
+				"x402 payment rejected, falling back",
+				slog.String("reason", rejection.Reason),
+				slog.String("network", paymentDetails.Network),
+				slog.String("asset", paymentDetails.Asset),
+			)
+		}
+
+		tried = append(tried, paymentDetails)
+
+		paidResp, payment, release, err := t.pay(req, body, candidate)
+		if err != nil {
+			return nil, err
+		}
+
+		if paidResp.StatusCode != http.StatusPaymentRequired {
+			t.cache.put(req, candidate)
+
+			return t.paid(req, paidResp, paymentDetails, payment), nil
+		}
+
+		// The payment wasn't accepted so it doesn't count against the
+		// budget.
+		release()
+
+		rejection = t.rejection(paidResp, paymentDetails, payment)
+	}
+
+	return nil, rejection
+}
+
+// pay creates a payment for the requirements and retries the request with
+// the payment attached.  The returned function removes the payment from
+// the budget and should be called if the payment is rejected.
+func (t *Transport) pay(req *http.Request, body *replayableBody, candidate offer) (*http.Response, *api.PaymentPayload, func(), error) {
+	paymentDetails := candidate.requirements
+
+	t.log.Debug(
+		"Payment requirements selected",
+		slog.String("scheme", paymentDetails.Scheme),
+		slog.String("network", paymentDetails.Network),
+		slog.String("asset", paymentDetails.Asset),
+		slog.String("amount", paymentDetails.MaxAmountRequired),
+	)
+
+	amount, ok := new(big.Int).SetString(paymentDetails.MaxAmountRequired, 10)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("invalid payment amount: %s", paymentDetails.MaxAmountRequired)
+	}
+
+	release, err := t.budget.reserve(req.URL.Hostname(), paymentDetails.Asset, amount)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := t.checkFunds(req.Context(), t.signer, paymentDetails, amount); err != nil {
+		release()
+
+		return nil, nil, nil, err
+	}
+
+	if err := t.approve(req.Context(), req.URL, paymentDetails, amount); err != nil {
+		release()
+
+		return nil, nil, nil, err
+	}
+
+	payer, ok := t.payers.lookup(paymentDetails)
+	if !ok {
+		release()
+
+		return nil, nil, nil, fmt.Errorf("%w: no payer for the %q scheme on %s", ErrNoAcceptablePayment, paymentDetails.Scheme, paymentDetails.Network)
+	}
+
+	ctx := api.ContextWithClockOffset(req.Context(), t.skew.offset(req.URL.Hostname()))
+
+	payment, err := api.PayContext(ctx, payer, paymentDetails)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create payment: %w", err)
+		release()
+
+		return nil, nil, nil, fmt.Errorf("failed to create payment: %w", err)
 	}
 
-	paymentData, err := json.Marshal(payment)
+	payment.X402Version = candidate.version
+
+	header, paymentData, err := candidate.encode(payment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payment: %w", err)
+		release()
+
+		return nil, nil, nil, err
 	}
 
 	t.log.Debug("Payment header JSON", slog.String("json", string(paymentData)))
 
-	req.Header.Set("X-Payment", base64.StdEncoding.EncodeToString(paymentData))
+	// Intercept the response with a copy of the request
+	if req.Body, err = body.next(); err != nil {
+		release()
+
+		return nil, nil, nil, err
+	}
 
-	return t.next.RoundTrip(req)
-}
+	req.Header.Del(headerPayment)
+	req.Header.Del(headerPaymentSignatureV2)
+	req.Header.Set(header, base64.StdEncoding.EncodeToString(paymentData))
 
-func (t *Transport) createPayment(details types.PaymentRequirements) (*types.PaymentPayload, error) {
-	payer, err := evm.NewExactEvm(t.signer, time.Now, api.DefaultNonce, t.log)
+	resp, err := t.next.RoundTrip(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	return payer.Pay(details)
+	return resp, payment, release, nil
+}
+
+// paid attaches a receipt describing the payment to the seller's response.
+func (t *Transport) paid(req *http.Request, resp *http.Response, paymentDetails types.PaymentRequirements, payment *api.PaymentPayload) *http.Response {
+	receipt := &Payment{
+		Requirements: paymentDetails,
+		Payload:      payment,
+	}
+
+	if header := settlementHeader(resp.Header); header != "" {
+		settlement, reported, err := decodeSettlement(header)
+		if err != nil {
+			t.log.Warn("invalid settlement response", tint.Err(err))
+		}
+
+		receipt.Settlement = settlement
+		receipt.Charged = charged(paymentDetails, settlement, reported)
+	}
+
+	withPayment(resp, req, receipt)
+
+	return resp
 }
@@ -1,110 +1,301 @@
 package buyer_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/coinbase/x402/go/pkg/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	buyer "github.com/selesy/x402-buyer"
 	"github.com/selesy/x402-buyer/internal/signer"
+	"github.com/selesy/x402-buyer/pkg/api"
 	"github.com/selesy/x402-buyer/pkg/api/apitest"
 )
 
 func TestTransport(t *testing.T) {
-	const payReq = `{"accepts":[{"scheme":"exact","network":"base","maxAmountRequired":"10000","resource":"https://example.com","description":"A premium programming joke","mimeType":"","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","extra":{"name":"USD Coin","version":"2"}}],"error":"X-PAYMENT header is required","x402Version":1}`
-
 	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
 	require.NoError(t, err)
 
-	t.Run("passes - no payment required", func(t *testing.T) {
-		t.Parallel()
-
-		respIn1 := &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(strings.NewReader("Response body")),
-		}
+	const (
+		// unknownNetworkReq offers a payment on an unknown network before
+		// one on Base.
+		unknownNetworkReq = `{"accepts":[{"scheme":"exact","network":"unknown","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"},{"scheme":"exact","network":"base","maxAmountRequired":"10000","resource":"https://example.com","description":"A premium programming joke","mimeType":"","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","extra":{"name":"USD Coin","version":"2"}}],"error":"X-PAYMENT header is required","x402Version":1}`
+		privateTokenReq   = `{"accepts":[{"scheme":"exact","network":"private-l2","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x5FbDB2315678afecb367f032d93F642f64180aa3","extra":{"name":"Private USD","version":"1"}}],"error":"X-PAYMENT header is required","x402Version":1}`
+		privateNetworkReq = `{"accepts":[{"scheme":"exact","network":"private-l2","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"}],"error":"X-PAYMENT header is required","x402Version":1}`
+		caip2Req          = `{"accepts":[{"scheme":"exact","network":"eip155:8453","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"}],"error":"X-PAYMENT header is required","x402Version":1}`
+		mismatchReq       = `{"accepts":[{"scheme":"exact","network":"base","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","extra":{"name":"Totally USD Coin","version":"2"}}],"error":"X-PAYMENT header is required","x402Version":1}`
+		invalidReq        = `{"accepts":[{"scheme":"exact","network":"base","maxAmountRequired":"ten","payTo":"nobody","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","extra":{"name":1}}],"error":"X-PAYMENT header is required","x402Version":1}`
+		fallbackReq       = `{"accepts":[{"scheme":"exact","network":"base-sepolia","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x036CbD53842c5426634e7929541eC2318f3dCF7e","extra":{"name":"USDC","version":"2"}},{"scheme":"exact","network":"base","maxAmountRequired":"10000","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","extra":{"name":"USD Coin","version":"2"}}],"error":"X-PAYMENT header is required","x402Version":1}`
+	)
+
+	for _, tc := range []struct {
+		name  string
+		resps func() []*http.Response
+		opts  func() []buyer.Option
+		// network is the network of the payment that's made.
+		network string
+	}{
+		{
+			name:  "no payment required",
+			resps: func() []*http.Response { return []*http.Response{okResponse()} },
+		},
+		{
+			name:    "payment required",
+			resps:   func() []*http.Response { return []*http.Response{paymentRequiredResponse(payReq), okResponse()} },
+			network: "base",
+		},
+		{
+			name: "unpayable requirements skipped",
+			resps: func() []*http.Response {
+				return []*http.Response{paymentRequiredResponse(unknownNetworkReq), okResponse()}
+			},
+			network: "base",
+		},
+		{
+			name: "registered network",
+			resps: func() []*http.Response {
+				return []*http.Response{paymentRequiredResponse(privateTokenReq), okResponse()}
+			},
+			opts: func() []buyer.Option {
+				return []buyer.Option{
+					buyer.WithNetwork("private-l2", 31337),
+					buyer.WithToken(api.Token{
+						ChainID:  big.NewInt(31337),
+						Address:  common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"),
+						Name:     "Private USD",
+						Version:  "1",
+						Decimals: 6,
+					}),
+				}
+			},
+			network: "private-l2",
+		},
+		{
+			name: "fallback after rejection",
+			resps: func() []*http.Response {
+				return []*http.Response{
+					paymentRequiredResponse(fallbackReq),
+					paymentRequiredResponse(`{"accepts":[],"error":"insufficient_funds","x402Version":1}`),
+					okResponse(),
+				}
+			},
+			opts:    func() []buyer.Option { return []buyer.Option{buyer.WithFallback()} },
+			network: "base",
+		},
+	} {
+		t.Run("passes - "+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []buyer.Option
+			if tc.opts != nil {
+				opts = tc.opts()
+			}
+
+			resps := tc.resps()
+
+			trans, _ := newTestTransport(t, resps, opts...)
+
+			respOut, err := doRequest(t, trans, "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, resps[len(resps)-1], respOut)
+
+			t.Cleanup(func() {
+				require.NoError(t, respOut.Body.Close())
+			})
+
+			payment, ok := buyer.PaymentFromResponse(respOut)
+			require.Equal(t, tc.network != "", ok)
+
+			if ok {
+				assert.Equal(t, tc.network, payment.Requirements.Network)
+			}
+		})
+	}
 
-		next := newMockTransport(t, respIn1)
-		trans, err := buyer.NewTransport(next, signer)
-		require.NoError(t, err)
+	for _, tc := range []struct {
+		name    string
+		network string
+		body    string
+	}{
+		{name: "registered payer", network: "private-l2", body: privateNetworkReq},
+		{name: "registered payer matched by CAIP-2 network", network: "base", body: caip2Req},
+	} {
+		t.Run("passes - "+tc.name, func(t *testing.T) {
+			t.Parallel()
 
-		req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
-		require.NoError(t, err)
+			payer := &mockPayer{}
 
-		respOut, err := trans.RoundTrip(req)
-		require.NoError(t, err)
-		assert.Equal(t, respIn1, respOut)
+			trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(tc.body), okResponse()}, buyer.WithPayer(payer, tc.network))
 
-		t.Cleanup(func() {
+			respOut, err := doRequest(t, trans, "https://example.com")
+			require.NoError(t, err)
 			require.NoError(t, respOut.Body.Close())
+			assert.Equal(t, 1, payer.calls)
 		})
+	}
+
+	t.Run("fails - nil payer", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := buyer.NewTransport(http.DefaultTransport, signer, buyer.WithPayer(nil))
+		require.Error(t, err)
+	})
+
+	t.Run("fails - token domain mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		trans, next := newTestTransport(t, []*http.Response{paymentRequiredResponse(mismatchReq)})
+
+		_, err := doRequest(t, trans, "https://example.com")
+		require.Error(t, err)
+		assert.Len(t, next.headers, 1)
 	})
 
-	t.Run("passes - payment required", func(t *testing.T) {
+	t.Run("fails - invalid payment requirements", func(t *testing.T) {
 		t.Parallel()
 
-		respIn1 := &http.Response{
-			StatusCode: http.StatusOK,
-			Body:       io.NopCloser(strings.NewReader("Response body")),
-		}
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(invalidReq)})
 
-		respIn2 := &http.Response{
-			StatusCode: http.StatusPaymentRequired,
-			Body:       io.NopCloser(strings.NewReader(payReq)),
-		}
+		_, err := doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrNoAcceptablePayment)
 
-		next := newMockTransport(t, respIn2, respIn1)
-		trans, err := buyer.NewTransport(next, signer)
-		require.NoError(t, err)
+		var validationErr *api.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 3)
+	})
 
-		req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
-		require.NoError(t, err)
+	t.Run("fails - no acceptable payment requirements", func(t *testing.T) {
+		t.Parallel()
 
-		respOut, err := trans.RoundTrip(req)
-		require.NoError(t, err)
-		assert.Equal(t, respIn1, respOut)
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq)}, buyer.WithSelector(buyer.SelectNetworks("base-sepolia")))
 
-		t.Cleanup(func() {
-			require.NoError(t, respOut.Body.Close())
-		})
+		_, err := doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrNoAcceptablePayment)
 	})
 
 	t.Run("fails - invalid payment header", func(t *testing.T) {
 		t.Parallel()
 
-		respIn2 := &http.Response{
-			StatusCode: http.StatusPaymentRequired,
-			Body:       io.NopCloser(strings.NewReader(payReq)),
-		}
+		trans, _ := newTestTransport(t, []*http.Response{paymentRequiredResponse(payReq), paymentRequiredResponse(payReq)})
+
+		_, err := doRequest(t, trans, "https://example.com")
+		require.ErrorIs(t, err, buyer.ErrPaymentRejected)
+
+		var rejectedErr *buyer.PaymentRejectedError
+		require.ErrorAs(t, err, &rejectedErr)
+		assert.Equal(t, "X-PAYMENT header is required", rejectedErr.Reason)
+		assert.Equal(t, "base", rejectedErr.Requirements.Network)
+		payload, ok := rejectedErr.Payload.ExactEvm()
+		require.True(t, ok)
+		assert.Equal(t, signer.Address().Hex(), payload.Authorization.From)
+	})
 
-		respIn3 := &http.Response{
-			StatusCode: http.StatusPaymentRequired,
-			Body:       io.NopCloser(strings.NewReader(payReq)),
-		}
+	t.Run("fails - no fallback for reason", func(t *testing.T) {
+		t.Parallel()
 
-		next := newMockTransport(t, respIn2, respIn3)
-		trans, err := buyer.NewTransport(next, signer)
-		require.NoError(t, err)
+		trans, _ := newTestTransport(t, []*http.Response{
+			paymentRequiredResponse(fallbackReq),
+			paymentRequiredResponse(`{"accepts":[],"error":"invalid_exact_evm_payload_signature","x402Version":1}`),
+		}, buyer.WithFallback())
 
-		req, err := http.NewRequest(http.MethodGet, "https://example.com", strings.NewReader("Request body"))
-		require.NoError(t, err)
+		_, err := doRequest(t, trans, "https://example.com")
 
-		respOut, err := trans.RoundTrip(req)
-		require.NoError(t, err)
-		assert.Equal(t, respIn3, respOut)
+		var rejectedErr *buyer.PaymentRejectedError
+		require.ErrorAs(t, err, &rejectedErr)
+		assert.Equal(t, "invalid_exact_evm_payload_signature", rejectedErr.Reason)
+		assert.Equal(t, "base-sepolia", rejectedErr.Requirements.Network)
 	})
 }
 
+// payReq is the body of a 402 Payment Required response that asks for
+// 0.01 USDC on Base.
+const payReq = `{"accepts":[{"scheme":"exact","network":"base","maxAmountRequired":"10000","resource":"https://example.com","description":"A premium programming joke","mimeType":"","payTo":"0x60ac86571E55F9735F00cE9e28361d203977B260","maxTimeoutSeconds":60,"asset":"0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913","extra":{"name":"USD Coin","version":"2"}}],"error":"X-PAYMENT header is required","x402Version":1}`
+
+// paymentRequiredResponse returns a 402 Payment Required response with the
+// provided body.
+func paymentRequiredResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusPaymentRequired,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// okResponse returns a 200 OK response.
+func okResponse() *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("Response body")),
+	}
+}
+
+// newTestTransport returns a Transport that pays using the apitest private
+// key and the mockTransport that it wraps, which returns resps in order.
+func newTestTransport(t *testing.T, resps []*http.Response, opts ...buyer.Option) (*buyer.Transport, *mockTransport) {
+	t.Helper()
+
+	signer, err := signer.NewECDSASignerFromHex(apitest.ECDSAPrivateKeyHex)
+	require.NoError(t, err)
+
+	next := newMockTransport(t, resps...)
+
+	trans, err := buyer.NewTransport(next, signer, opts...)
+	require.NoError(t, err)
+
+	return trans, next
+}
+
+// doRequest sends a GET request for url, with the body expected by the
+// mockTransport, using trans.
+func doRequest(t *testing.T, trans http.RoundTripper, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, strings.NewReader("Request body"))
+	require.NoError(t, err)
+
+	return trans.RoundTrip(req)
+}
+
+var _ api.Payer = (*mockPayer)(nil)
+
+type mockPayer struct {
+	calls int
+}
+
+func (p *mockPayer) Pay(requirements types.PaymentRequirements) (*types.PaymentPayload, error) {
+	p.calls++
+
+	return &types.PaymentPayload{
+		X402Version: 1,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+	}, nil
+}
+
+func (p *mockPayer) Scheme() api.Scheme {
+	return api.SchemeExact
+}
+
 var _ http.RoundTripper = (*mockTransport)(nil)
 
 type mockTransport struct {
-	t     *testing.T
-	resps []*http.Response
-	idx   int
+	t       *testing.T
+	resps   []*http.Response
+	headers []http.Header
+	idx     int
 }
 
 func newMockTransport(t *testing.T, resps ...*http.Response) *mockTransport {
@@ -128,8 +319,72 @@ func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	require.False(t.t, t.idx >= len(t.resps), "Why?")
 
+	t.headers = append(t.headers, req.Header.Clone())
+
 	out := t.resps[t.idx]
 	t.idx++
 
 	return out, nil
 }
+
+func TestTransportClose(t *testing.T) {
+	t.Parallel()
+
+	// HTTP JSON-RPC clients don't hold a connection so an IPC endpoint is
+	// used.
+	service := &ethService{}
+
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", service))
+	t.Cleanup(server.Stop)
+
+	endpoint := filepath.Join(t.TempDir(), "rpc.ipc")
+
+	listener, err := net.Listen("unix", endpoint)
+	require.NoError(t, err)
+
+	go server.ServeListener(listener)
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	trans, _ := newTestTransport(t, []*http.Response{
+		paymentRequiredResponse(payReq),
+		okResponse(),
+		paymentRequiredResponse(payReq),
+	}, buyer.WithRPC("base", endpoint))
+
+	resp, err := doRequest(t, trans, "https://example.com")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	before := service.calls.Load()
+	assert.Positive(t, before)
+
+	require.NoError(t, trans.Close())
+
+	// The endpoint isn't called once the Transport is closed.
+	_, err = doRequest(t, trans, "https://example.com/other")
+	require.ErrorIs(t, err, rpc.ErrClientQuit)
+
+	assert.Equal(t, before, service.calls.Load())
+}
+
+// ethService is a JSON-RPC eth_call implementation that reports a large
+// token balance and that nonces haven't been used.
+type ethService struct {
+	calls atomic.Int32
+}
+
+func (s *ethService) Call(msg map[string]any, _ json.RawMessage) (hexutil.Bytes, error) {
+	s.calls.Add(1)
+
+	result := make([]byte, 32)
+
+	if input, _ := msg["input"].(string); strings.HasPrefix(input, "0x70a08231") {
+		result = bytes.Repeat([]byte{0xff}, 32)
+	}
+
+	return result, nil
+}